@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+)
+
+// 投稿テーブルの全記事の listing・listingDate (日付のインデックスのパーティションキー・ソートキー) を公開の状態に合わせる
+// 公開時・アーカイブ時に付け外しするため、日付のインデックスを作る前に公開した記事の移行と、
+// 付け外しに失敗した場合の復旧に使う (手動実行を想定している)
+// listingDate は posts.ListingDate で date (自由形式) と publishedAt から計算し直す
+// version・updatedAt は変えない (記事の内容は変わらない)
+
+// Report: 実行結果
+type Report struct {
+	Listed   int `json:"listed"`   // listing・listingDate を付けた (付け直した) 記事の数
+	Unlisted int `json:"unlisted"` // listing・listingDate を取り除いた記事の数
+}
+
+var dbClient *dynamodb.Client
+var postsTableName = os.Getenv("POSTS_TABLE_NAME") // 投稿テーブル名
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+}
+
+func Handler(ctx context.Context) (Report, error) {
+	fmt.Println("Received request for backfill date index handler.")
+
+	paginator := dynamodb.NewScanPaginator(dbClient, &dynamodb.ScanInput{
+		TableName:                aws.String(postsTableName),
+		ProjectionExpression:     aws.String("id, isPublished, archived, listing, listingDate, #date, publishedAt"),
+		ExpressionAttributeNames: map[string]string{"#date": "date"},
+	})
+	var report Report
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return report, fmt.Errorf("failed to scan posts: %w", err)
+		}
+		for _, item := range page.Items {
+			id, _ := item["id"].(*types.AttributeValueMemberS)
+			if id == nil {
+				continue
+			}
+			published, _ := item["isPublished"].(*types.AttributeValueMemberBOOL)
+			archived, _ := item["archived"].(*types.AttributeValueMemberBOOL)
+			listing := stringValue(item["listing"])
+			listingDate := stringValue(item["listingDate"])
+			date := posts.ListingDate(stringValue(item["date"]), stringValue(item["publishedAt"]))
+
+			listed := published != nil && published.Value && (archived == nil || !archived.Value) && date != ""
+			switch {
+			case listed && (listing != posts.ListingPublished || listingDate != date):
+				if err := update(ctx, id.Value, "SET listing = :listing, listingDate = :listingDate", map[string]types.AttributeValue{
+					":listing":     &types.AttributeValueMemberS{Value: posts.ListingPublished},
+					":listingDate": &types.AttributeValueMemberS{Value: date},
+				}); err != nil {
+					return report, err
+				}
+				report.Listed++
+			case !listed && (listing != "" || listingDate != ""):
+				if err := update(ctx, id.Value, "REMOVE listing, listingDate", nil); err != nil {
+					return report, err
+				}
+				report.Unlisted++
+			}
+		}
+	}
+
+	fmt.Printf("Backfilled date index: %d post(s) listed, %d post(s) unlisted\n", report.Listed, report.Unlisted)
+	return report, nil
+}
+
+// stringValue は文字列の属性の値を返す (無い場合は空文字列)
+func stringValue(av types.AttributeValue) string {
+	if s, ok := av.(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
+
+// update は記事が削除されていない場合だけ listing・listingDate を書き換える
+func update(ctx context.Context, id, expression string, values map[string]types.AttributeValue) error {
+	_, err := dbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(postsTableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       aws.String("attribute_exists(id)"),
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update listing of %s: %w", id, err)
+	}
+	return nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/attachments"
+)
+
+// blog_drafts テーブルの DynamoDB Streams を購読し、削除された下書きの添付ファイルをS3から削除する
+// TTLによる自動削除とユーザー操作による削除 (delete-draft) の両方が対象
+// 公開済みの下書き (publish-post が投稿テーブルへ移したもの) は添付ファイルを投稿側で使い続けるため削除しない
+
+var dbClient *dynamodb.Client
+var postsTableName = os.Getenv("POSTS_TABLE_NAME") // 投稿テーブル名
+
+var s3Client *s3.Client
+var bucketName = os.Getenv("BUCKET_NAME")
+
+var cleanupTableName = os.Getenv("CLEANUP_TABLE_NAME") // 削除に失敗したS3オブジェクトを記録するテーブル名
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+	s3Client = s3.NewFromConfig(cfg)
+}
+
+// Handler handles DynamoDB Streams events from the drafts table.
+// 処理に失敗したレコードは BatchItemFailures として返し、Lambdaに再試行させる
+func Handler(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	fmt.Printf("Received %d stream record(s) for cleanup attachments handler.\n", len(event.Records))
+
+	var response events.DynamoDBEventResponse
+
+	for _, record := range event.Records {
+		if record.EventName != string(events.DynamoDBOperationTypeRemove) {
+			continue
+		}
+
+		draftID := record.Change.Keys["id"].String()
+		if draftID == "" {
+			fmt.Printf("Skipping record %s: missing draft ID\n", record.EventID)
+			continue
+		}
+
+		if err := cleanupDraft(ctx, draftID, removalReason(record)); err != nil {
+			fmt.Printf("Error cleaning up attachments for draft %s: %v\n", draftID, err)
+			response.BatchItemFailures = append(response.BatchItemFailures, events.DynamoDBBatchItemFailure{
+				ItemIdentifier: record.Change.SequenceNumber,
+			})
+		}
+	}
+
+	return response, nil
+}
+
+// cleanupDraft は公開されていない下書きの添付ファイルを削除する
+func cleanupDraft(ctx context.Context, draftID, reason string) error {
+	published, err := isPublished(ctx, draftID)
+	if err != nil {
+		return fmt.Errorf("failed to check posts table: %w", err)
+	}
+	if published {
+		fmt.Printf("Draft %s was published, keeping attachments\n", draftID)
+		return nil
+	}
+
+	keys, err := attachments.ListKeys(ctx, s3Client, bucketName, attachments.Prefix(draftID))
+	if err != nil {
+		return fmt.Errorf("failed to list attachments: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Deleting %d attachment(s) of draft %s (%s)\n", len(keys), draftID, reason)
+	failed := attachments.DeleteObjects(ctx, s3Client, bucketName, keys)
+	// 個別の削除失敗はレコードを再試行するのではなく、スイーパーに任せる
+	attachments.RecordPendingDeletions(ctx, dbClient, cleanupTableName, bucketName, reason, failed)
+
+	return nil
+}
+
+// isPublished は下書きと同じIDの記事が投稿テーブルに存在するかを返す
+func isPublished(ctx context.Context, draftID string) (bool, error) {
+	result, err := dbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(postsTableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: draftID},
+		},
+		ProjectionExpression: aws.String("id"),
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.Item != nil, nil
+}
+
+// removalReason はTTLによる削除かユーザー操作による削除かを判別する
+func removalReason(record events.DynamoDBEventRecord) string {
+	if record.UserIdentity != nil &&
+		record.UserIdentity.Type == "Service" &&
+		record.UserIdentity.PrincipalID == "dynamodb.amazonaws.com" {
+		return "draft expired (TTL)"
+	}
+	return "draft deleted"
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -19,8 +19,17 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/attachments"
+	"github.com/sunshine-724/my-homepage-backend/internal/drafts"
+	"github.com/sunshine-724/my-homepage-backend/internal/idempotency"
+	"github.com/sunshine-724/my-homepage-backend/internal/publish"
+	"github.com/sunshine-724/my-homepage-backend/internal/revision"
+	"github.com/sunshine-724/my-homepage-backend/internal/timestamp"
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
 )
 
 // TODO: ロギングをfmtからzerologのような構造化ロギングライブラリに移行する (LOG_LEVEL環境変数で制御)
@@ -29,10 +38,13 @@ import (
 // RequestBody: フロントエンドから送られてくるリクエストボディ
 type RequestBody struct {
 	Title       string   `json:"title"`
-	Date        string   `json:"date"`
+	Date        string   `json:"date"` // 表示用の日付。省略した場合は SITE_TIMEZONE (既定値 Asia/Tokyo) での今日の日付
 	Content     string   `json:"content"`
 	Tags        []string `json:"tags"`
 	IsPublished bool     `json:"isPublished"`
+	NoIndex     bool     `json:"noIndex"`
+	PublishAt   string   `json:"publishAt"` // 予約公開の日時 (RFC 3339)。省略した場合は予約しない
+	ExpireAt    string   `json:"expireAt"`  // 公開終了の日時 (RFC 3339)。過ぎると記事は配信されなくなる
 }
 
 // DraftItem: DynamoDBに保存するデータ構造
@@ -44,7 +56,13 @@ type DraftItem struct {
 	Tags               []string `dynamodbav:"tags"`
 	AttachmentFilePath []string `dynamodbav:"attachmentFilePath"` // S3に保存したファイルのパス
 	IsPublished        bool     `dynamodbav:"isPublished"`
+	NoIndex            bool     `dynamodbav:"noindex,omitempty"` // 公開後に検索エンジンにインデックスさせない
+	PublishAt          string   `dynamodbav:"publishAt,omitempty"` // 予約公開の日時 (UTCのRFC 3339)
+	ExpireAt           string   `dynamodbav:"expireAt,omitempty"`  // 公開終了の日時 (UTCのRFC 3339、公開後の記事に引き継ぐ)
 	TTL                int64    `dynamodbav:"ttl"`
+	Version            int      `dynamodbav:"version"` // 楽観的排他制御のversion (作成時は1)
+	CreatedAt          string   `dynamodbav:"createdAt"` // 作成した日時 (UTCのRFC 3339)
+	UpdatedAt          string   `dynamodbav:"updatedAt"` // 最後に更新した日時 (UTCのRFC 3339)
 }
 
 var dbClient *dynamodb.Client
@@ -54,6 +72,11 @@ var s3Client *s3.Client
 var bucketName = os.Getenv("BUCKET_NAME")
 var region = os.Getenv("AWS_REGION") // AWS側で環境変数を取得してくれる
 
+var cleanupTableName = os.Getenv("CLEANUP_TABLE_NAME") // 削除に失敗したS3オブジェクトを記録するテーブル名
+var revisionsTableName = os.Getenv("REVISIONS_TABLE_NAME") // リビジョン (履歴) のテーブル名
+
+var idempotencyStore *idempotency.Store // Idempotency-Key の記録 (IDEMPOTENCY_TABLE_NAME が未設定の場合は使わない)
+
 func init() {
 	// v2ではconfig.LoadDefaultConfigを使って設定をロード
 	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
@@ -63,9 +86,21 @@ func init() {
 	// DynamoDBクライアントをv2で作成
 	dbClient = dynamodb.NewFromConfig(cfg)
 	s3Client = s3.NewFromConfig(cfg)
+	idempotencyStore = &idempotency.Store{Client: dbClient, Table: os.Getenv("IDEMPOTENCY_TABLE_NAME")}
+	if idempotencyStore.Table == "" {
+		fmt.Fprintln(os.Stderr, "Warning: IDEMPOTENCY_TABLE_NAME is not set; Idempotency-Key headers will not prevent duplicate requests")
+	}
 }
 
+// Handler は Idempotency-Key 付きの再試行 (タイムアウトやダブルクリック) で下書きが二重に作成されないようにする
+// 同じキーの再試行には最初のレスポンス (作成した下書きのID) をそのまま返す
 func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return idempotencyStore.Do(ctx, "create-draft", request, func() (events.APIGatewayProxyResponse, error) {
+		return createDraft(ctx, request)
+	})
+}
+
+func createDraft(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	/* 入力処理 */
 	contentType := request.Headers["content-type"]
 	if contentType == "" {
@@ -85,9 +120,21 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	var draftID string // dynamoDBの主キー
 	var ttl int64
 
+	now := time.Now()
 	draftID = uuid.New().String()
 	fmt.Println("Generated draftID:", draftID)
-	ttl = time.Now().Add(7 * 24 * time.Hour).Unix()
+	ttl = drafts.TTL(now) // 保持期間は DRAFT_TTL で変更できる
+
+	// 下書きの作成は all-or-nothing とする
+	// DynamoDBへの保存まで完了しなかった場合は、それまでにS3へアップロードしたファイルを削除する
+	committed := false
+	defer func() {
+		if committed || len(attachmentFilePaths) == 0 {
+			return
+		}
+		// Lambdaのタイムアウトでctxがキャンセルされていても削除は実行する
+		rollbackUploads(context.WithoutCancel(ctx), attachmentFilePaths)
+	}()
 
 	if mediaType == "application/json" {
 		/* 送られてきたのがJSONのテキスト形式だった場合 */
@@ -200,6 +247,12 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 					}
 				case "isPublished":
 					reqBody.IsPublished = (fieldValue == "true")
+				case "noIndex", "noindex": // noindex は以前のフィールド名
+					reqBody.NoIndex = (fieldValue == "true")
+				case "publishAt":
+					reqBody.PublishAt = fieldValue
+				case "expireAt":
+					reqBody.ExpireAt = fieldValue
 				}
 			}
 			loopCounter++
@@ -209,15 +262,35 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	}
 
 	/* DB処理 */
+	publishAt, err := publish.ParsePublishAt(reqBody.PublishAt)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: err.Error()}, nil
+	}
+	expireAt, err := publish.ParseExpireAt(reqBody.ExpireAt)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: err.Error()}, nil
+	}
+	if err := publish.ValidateSchedule(publishAt, expireAt); err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: err.Error()}, nil
+	}
+	// 予約公開する下書きは公開日時より前に期限切れにならないようにする
+	ttl = publish.ScheduledTTL(ttl, publishAt)
+
 	item = DraftItem{
 		ID:                 draftID,
 		Title:              reqBody.Title,
-		Date:               reqBody.Date,
+		Date:               timestamp.Date(reqBody.Date, now),
 		Content:            reqBody.Content,
 		Tags:               reqBody.Tags,
 		AttachmentFilePath: attachmentFilePaths,
 		IsPublished:        false,
+		NoIndex:            reqBody.NoIndex,
+		PublishAt:          publishAt,
+		ExpireAt:           expireAt,
 		TTL:                ttl,
+		Version:            1,
+		CreatedAt:          timestamp.Format(now),
+		UpdatedAt:          timestamp.Format(now),
 	}
 	av, err := attributevalue.MarshalMap(item) // Goの構造体の形からDynamoDBの形に変換
 
@@ -226,31 +299,53 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	}
 
 	// input変数に値を入れる直前のTableNameの値をログ出力
-	fmt.Println("TableName passed to PutItemInput:", tableName)
+	fmt.Println("TableName passed to TransactWriteItems:", tableName)
+
+	// 下書きの保存と最初のリビジョンの記録を1つのトランザクションで行う
+	revisionPut, err := revision.Put(revisionsTableName, revision.New(
+		draftID, 1, item.Title, item.Date, item.Content, item.Tags, revision.AuthorFromRequest(request), revision.SourceDraft,
+	))
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to marshal revision: %v", err)}, nil
+	}
 
-	// v2のPutItemInputを作成し、PutItemを実行
-	input := &dynamodb.PutItemInput{
-		Item:      av,
-		TableName: aws.String(tableName),
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{Item: av, TableName: aws.String(tableName)}},
+			revisionPut,
+		},
 	}
 
 	fmt.Println("About to save item with ID:", item.ID)
 
-	_, err = dbClient.PutItem(ctx, input)
+	_, err = dbClient.TransactWriteItems(ctx, input)
 	if err != nil {
 		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to put item to DynamoDB: %v", err)}, nil
 	}
 
+	committed = true
 	fmt.Println("Saved draftID to DynamoDB:", draftID)
 
-	responseBody, _ := json.Marshal(map[string]string{"id": draftID}) // Goの構造体からJSONの形に変換
+	responseBody, _ := json.Marshal(map[string]any{"id": draftID, "version": item.Version}) // Goの構造体からJSONの形に変換
 	return events.APIGatewayProxyResponse{
 		StatusCode: 200,
-		Headers:    map[string]string{"Content-Type": "application/json"},
+		Headers:    map[string]string{"Content-Type": "application/json", "ETag": version.ETag(item.Version)},
 		Body:       string(responseBody),
 	}, nil
 }
 
+// rollbackUploads は下書き作成の途中でアップロードしたファイルを削除する
+// 削除できなかったファイルはスイーパーが再試行できるようにCLEANUP_TABLE_NAMEへ記録する
+func rollbackUploads(ctx context.Context, keys []string) {
+	fmt.Printf("Rolling back %d uploaded file(s)\n", len(keys))
+
+	failed := attachments.DeleteObjects(ctx, s3Client, bucketName, keys)
+	if len(failed) == 0 {
+		return
+	}
+	attachments.RecordPendingDeletions(ctx, dbClient, cleanupTableName, bucketName, "create-draft rollback", failed)
+}
+
 func main() {
 	lambda.Start(Handler)
 }
@@ -12,6 +12,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
 )
 
 var dbClient *dynamodb.Client
@@ -39,6 +41,12 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Missing draft ID"}, nil
 	}
 
+	// 他のタブなどで更新された下書きを削除しないよう、If-Match の version と一致する場合だけ削除する
+	expected, err := version.IfMatch(request)
+	if response, ok := version.ErrorResponse(err); ok {
+		return response, nil
+	}
+
 	// Create DeleteItemInput for DynamoDB
 	deleteItemInput := &dynamodb.DeleteItemInput{
 		TableName: aws.String(draftsTableName),
@@ -46,10 +54,27 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 			"id": &types.AttributeValueMemberS{Value: draftID},
 		},
 	}
+	if expected != version.Any {
+		condition, names, values := version.Condition(expected)
+		deleteItemInput.ConditionExpression = aws.String("attribute_exists(id) AND " + condition)
+		deleteItemInput.ExpressionAttributeNames = names
+		deleteItemInput.ExpressionAttributeValues = values
+	}
 
 	// Delete the item from the DynamoDB drafts table
 	fmt.Printf("Deleting item with ID: %s from drafts table: %s\n", draftID, draftsTableName)
-	_, err := dbClient.DeleteItem(ctx, deleteItemInput)
+	_, err = dbClient.DeleteItem(ctx, deleteItemInput)
+	if version.ConditionFailed(err, 0) {
+		mismatch, err := version.Conflict(ctx, dbClient, draftsTableName, draftID)
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get draft: %v", err)}, nil
+		}
+		if mismatch == nil {
+			return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Draft with ID %s not found", draftID)}, nil
+		}
+		response, _ := version.ErrorResponse(mismatch)
+		return response, nil
+	}
 	if err != nil {
 		fmt.Printf("Error deleting item from DynamoDB: %v\n", err)
 		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to delete draft: %v", err)}, nil
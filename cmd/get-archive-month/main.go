@@ -0,0 +1,81 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/httpcache"
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+)
+
+// GET /archive/{year}/{month} 指定した年・月の公開中の記事を日付の新しい順に返す
+// 日付のインデックスを date の前方一致 (YYYY-MM) で読み、投稿テーブルは走査しない
+
+// Response: レスポンスボディ
+type Response struct {
+	Year  int             `json:"year"`
+	Month int             `json:"month"`
+	Total int             `json:"total"`
+	Posts []posts.Summary `json:"posts"`
+}
+
+// defaultCacheControl: CACHE_CONTROL でルートのポリシーが設定されていない場合の Cache-Control (GET /posts と同じ)
+const defaultCacheControl = "no-cache"
+
+var dbClient *dynamodb.Client
+var postsTableName = os.Getenv("POSTS_TABLE_NAME")                               // 投稿テーブル名
+var dateIndexName = cmp.Or(os.Getenv("DATE_INDEX_NAME"), posts.DefaultDateIndex) // 日付のインデックス (投稿テーブルのGSI)
+
+var cachePolicies = httpcache.PoliciesFromEnv()
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+}
+
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Println("Received request for get archive month handler.")
+
+	year, err := strconv.Atoi(request.PathParameters["year"])
+	if err != nil || year < 1 || year > 9999 {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Invalid year: %s", request.PathParameters["year"])}, nil
+	}
+	month, err := strconv.Atoi(request.PathParameters["month"])
+	if err != nil || month < 1 || month > 12 {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Invalid month: %s", request.PathParameters["month"])}, nil
+	}
+
+	dateIndex := posts.DateIndex{Client: dbClient, TableName: postsTableName, IndexName: dateIndexName}
+	summaries, err := dateIndex.InMonth(ctx, year, month, time.Now())
+	if err != nil {
+		fmt.Printf("Error listing posts in %04d-%02d: %v\n", year, month, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get archive: %v", err)}, nil
+	}
+
+	responseBody, err := json.Marshal(Response{Year: year, Month: month, Total: len(summaries), Posts: summaries})
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to marshal response: %v", err)}, nil
+	}
+
+	// ETag はレスポンスボディのハッシュ (記事の公開・アーカイブで変わる)
+	validators := httpcache.Validators{ETag: httpcache.ETag(responseBody)}
+	cacheControl := cachePolicies.For(httpcache.Route(request), defaultCacheControl)
+	return httpcache.Respond(request, map[string]string{"Content-Type": "application/json"}, string(responseBody), validators, cacheControl), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
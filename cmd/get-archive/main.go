@@ -0,0 +1,72 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/httpcache"
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+)
+
+// GET /archive 公開中の記事の数を年・月ごとに返す (日付のインデックスから読み、投稿テーブルは走査しない)
+
+// Response: レスポンスボディ
+type Response struct {
+	Total int          `json:"total"` // 公開中の記事の総数
+	Years []posts.Year `json:"years"` // 新しい年から順に並べる
+}
+
+// defaultCacheControl: CACHE_CONTROL でルートのポリシーが設定されていない場合の Cache-Control (GET /posts と同じ)
+const defaultCacheControl = "no-cache"
+
+var dbClient *dynamodb.Client
+var postsTableName = os.Getenv("POSTS_TABLE_NAME")                               // 投稿テーブル名
+var dateIndexName = cmp.Or(os.Getenv("DATE_INDEX_NAME"), posts.DefaultDateIndex) // 日付のインデックス (投稿テーブルのGSI)
+
+var cachePolicies = httpcache.PoliciesFromEnv()
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+}
+
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Println("Received request for get archive handler.")
+
+	dateIndex := posts.DateIndex{Client: dbClient, TableName: postsTableName, IndexName: dateIndexName}
+	years, err := dateIndex.Months(ctx, time.Now())
+	if err != nil {
+		fmt.Printf("Error counting posts by month: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get archive: %v", err)}, nil
+	}
+
+	response := Response{Years: years}
+	for _, year := range years {
+		response.Total += year.Count
+	}
+	responseBody, err := json.Marshal(response)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to marshal response: %v", err)}, nil
+	}
+
+	// ETag はレスポンスボディのハッシュ (記事の公開・アーカイブで変わる)
+	validators := httpcache.Validators{ETag: httpcache.ETag(responseBody)}
+	cacheControl := cachePolicies.For(httpcache.Route(request), defaultCacheControl)
+	return httpcache.Respond(request, map[string]string{"Content-Type": "application/json"}, string(responseBody), validators, cacheControl), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
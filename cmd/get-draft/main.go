@@ -5,32 +5,79 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/attachments"
+	"github.com/sunshine-724/my-homepage-backend/internal/markdown"
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
 )
 
-type RequestBody struct {
-	ID string `json:"id"`
+// presignExpiry: 添付ファイルの署名付きURLの有効期間
+const presignExpiry = 15 * time.Minute
+
+// DraftItem: DynamoDBの下書きテーブルに保存されているデータ構造
+type DraftItem struct {
+	ID                 string   `dynamodbav:"id"`
+	Title              string   `dynamodbav:"title"`
+	Date               string   `dynamodbav:"date"`
+	Content            string   `dynamodbav:"content"`
+	Tags               []string `dynamodbav:"tags"`
+	AttachmentFilePath []string `dynamodbav:"attachmentFilePath"` // S3に保存したファイルのパス
+	IsPublished        bool     `dynamodbav:"isPublished"`
+	PublishAt          string   `dynamodbav:"publishAt,omitempty"` // 予約公開の日時
+	ExpireAt           string   `dynamodbav:"expireAt,omitempty"`  // 公開終了の日時
+	Pinned             bool     `dynamodbav:"pinned,omitempty"`
+	TTL                int64    `dynamodbav:"ttl"`
+	Version            int      `dynamodbav:"version"`
+	CreatedAt          string   `dynamodbav:"createdAt"`
+	UpdatedAt          string   `dynamodbav:"updatedAt"`
 }
 
-type PostItem struct {
-    ID          string   `json:"id" dynamodbav:"id"`
-    Title       string   `json:"title" dynamodbav:"title"`
-    Date        string   `json:"date" dynamodbav:"date"`
-    Content     string   `json:"content" dynamodbav:"content"`
-    Tags        []string `json:"tags" dynamodbav:"tags"`
-    IsPublished bool     `json:"isPublished" dynamodbav:"isPublished"`
-    TTL         int64    `json:"ttl" dynamodbav:"ttl"`
+// Attachment: 添付ファイルと、プレビュー用の署名付きURL
+type Attachment struct {
+	Key  string `json:"key"`  // S3のオブジェクトキー ({draftID}/{fileName})
+	Name string `json:"name"` // ファイル名
+	URL  string `json:"url"`  // 署名付きURL (presignExpiryの間だけ有効)
 }
 
+// Draft: GET /drafts/{id} のレスポンス
+type Draft struct {
+	ID                 string       `json:"id"`
+	Title              string       `json:"title"`
+	Date               string       `json:"date"`
+	Content            string       `json:"content"`
+	ContentHTML        string       `json:"contentHtml"` // プレビュー用にレンダリングしたHTML
+	Tags               []string     `json:"tags"`
+	IsPublished        bool         `json:"isPublished"`
+	PublishAt          string       `json:"publishAt,omitempty"` // 予約公開の日時 (RFC 3339)
+	ExpireAt           string       `json:"expireAt,omitempty"`  // 公開終了の日時 (RFC 3339)
+	Pinned             bool         `json:"pinned,omitempty"`    // 固定されている (期限切れにならない)
+	TTL                int64        `json:"ttl"`
+	Version            int          `json:"version"`             // 楽観的排他制御のversion (ETagヘッダーと同じ値)
+	ExpiresAt          string       `json:"expiresAt,omitempty"` // TTLをRFC 3339に変換したもの
+	CreatedAt          string       `json:"createdAt,omitempty"` // 作成した日時 (UTCのRFC 3339)
+	UpdatedAt          string       `json:"updatedAt,omitempty"` // 最後に更新した日時 (UTCのRFC 3339)
+	AttachmentFilePath []string     `json:"attachmentFilePath"`
+	Attachments        []Attachment `json:"attachments"`
+	Warnings           []string     `json:"warnings,omitempty"` // どの添付ファイルにも一致しなかった本文中の参照など
+}
 
 var dbClient *dynamodb.Client
-var getTableName = os.Getenv("GET_TABLE_NAME")
+var draftsTableName = os.Getenv("DRAFTS_TABLE_NAME") // 下書きテーブル名
+
+var s3Client *s3.Client
+var presignClient *s3.PresignClient
+var bucketName = os.Getenv("BUCKET_NAME")
 
 func init() {
 	cfg, err := config.LoadDefaultConfig(context.TODO())
@@ -38,36 +85,31 @@ func init() {
 		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
 	}
 	dbClient = dynamodb.NewFromConfig(cfg)
+	s3Client = s3.NewFromConfig(cfg)
+	presignClient = s3.NewPresignClient(s3Client)
 }
 
+// Handler handles the API Gateway proxy request to get a draft for the preview page.
 func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	fmt.Println("Received request for get posts handler.")
+	fmt.Println("Received request for get draft handler.")
 
 	id := request.PathParameters["id"]
 
-	if(id == "") {
+	if id == "" {
 		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Invalid request Body"}, nil
 	}
 
-	// キーを定義
-	key := map[string]types.AttributeValue{
-		"id": &types.AttributeValueMemberS{Value: id},
-	}
-
-	// GetItemの構造体を作成
 	input := &dynamodb.GetItemInput{
-		TableName: &getTableName,
-		Key:       key,
-	}
-
-	if v, ok := input.Key["id"].(*types.AttributeValueMemberS); ok {
-		fmt.Printf("TableName: %s, Key: %s\n", *input.TableName, v.Value)
-	} else {
-		fmt.Printf("TableName: %s, Key: (not a string)\n", *input.TableName)
+		TableName: aws.String(draftsTableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
 	}
+	fmt.Printf("TableName: %s, Key: %s\n", draftsTableName, id)
 
 	result, err := dbClient.GetItem(ctx, input)
 	if err != nil {
+		fmt.Printf("Error getting item from DynamoDB: %v\n", err)
 		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "アイテムの取得に失敗しました"}, nil
 	}
 
@@ -75,23 +117,85 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		return events.APIGatewayProxyResponse{StatusCode: 404, Body: "指定された主キーを持つアイテムは見つかりませんでした\n"}, nil
 	}
 
-	var postItem PostItem
-	err = attributevalue.UnmarshalMap(result.Item, &postItem) // parse
+	var draftItem DraftItem
+	err = attributevalue.UnmarshalMap(result.Item, &draftItem) // parse
 	if err != nil {
 		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "アイテムのパースに失敗しました"}, nil
 	}
 
-	responseBody, err := json.Marshal(postItem)
+	// DynamoDBのTTLによる削除は即時ではないため、期限切れでまだ削除されていないアイテムは存在しないものとして扱う
+	if draftItem.TTL > 0 && draftItem.TTL <= time.Now().Unix() {
+		fmt.Printf("Draft %s expired at %d\n", id, draftItem.TTL)
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: "指定された主キーを持つアイテムは見つかりませんでした\n"}, nil
+	}
+
+	draft := Draft{
+		ID:                 draftItem.ID,
+		Title:              draftItem.Title,
+		Date:               draftItem.Date,
+		Content:            draftItem.Content,
+		Tags:               draftItem.Tags,
+		IsPublished:        draftItem.IsPublished,
+		PublishAt:          draftItem.PublishAt,
+		ExpireAt:           draftItem.ExpireAt,
+		Pinned:             draftItem.Pinned,
+		TTL:                draftItem.TTL,
+		Version:            draftItem.Version,
+		CreatedAt:          draftItem.CreatedAt,
+		UpdatedAt:          draftItem.UpdatedAt,
+		AttachmentFilePath: draftItem.AttachmentFilePath,
+		Attachments:        []Attachment{},
+	}
+	if draftItem.TTL > 0 {
+		draft.ExpiresAt = time.Unix(draftItem.TTL, 0).UTC().Format(time.RFC3339)
+	}
+
+	// 添付ファイルは非公開のため、プレビューでは署名付きURLを使う
+	urls := map[string]string{}
+	for _, key := range draftItem.AttachmentFilePath {
+		presigned, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(key),
+		}, s3.WithPresignExpires(presignExpiry))
+		if err != nil {
+			fmt.Printf("Error presigning %s: %v\n", key, err)
+			return events.APIGatewayProxyResponse{StatusCode: 500, Body: "添付ファイルのURLの作成に失敗しました"}, nil
+		}
+		urls[key] = presigned.URL
+		draft.Attachments = append(draft.Attachments, Attachment{
+			Key:  key,
+			Name: path.Base(key),
+			URL:  presigned.URL,
+		})
+	}
+
+	var renderAttachments []markdown.Attachment
+	for _, file := range attachments.Group(draftItem.AttachmentFilePath) {
+		attachment := markdown.Attachment{Name: file.Name, URL: urls[file.Key]}
+		for _, v := range file.Variants {
+			attachment.Variants = append(attachment.Variants, markdown.Variant{URL: urls[v.Key], Width: v.Width})
+		}
+		renderAttachments = append(renderAttachments, attachment)
+	}
+
+	rendered, err := markdown.Render(draftItem.Content, markdown.Options{Attachments: renderAttachments})
 	if err != nil {
-		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "レスポンスボディの作成に失敗しました"}, nil
+		fmt.Printf("Error rendering content: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "本文のレンダリングに失敗しました"}, nil
 	}
+	draft.ContentHTML = rendered.HTML
+	draft.Warnings = rendered.Warnings
 
-	fmt.Println("Response Body: " + string(responseBody))
+	responseBody, err := json.Marshal(draft)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "レスポンスボディの作成に失敗しました"}, nil
+	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: 200,
 		Headers: map[string]string{
 			"Content-Type": "application/json",
+			"ETag":         version.ETag(draft.Version),
 		},
 		Body: string(responseBody),
 	}, nil
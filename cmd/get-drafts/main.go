@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// GET /drafts 下書きの一覧を返す (本文は含めない)
+// ?scheduled=true の場合は予約公開の日時が設定された下書きだけを、公開日時の早い順に返す
+
+// DraftSummary: 一覧に含める下書きの情報
+type DraftSummary struct {
+	ID          string   `json:"id" dynamodbav:"id"`
+	Title       string   `json:"title" dynamodbav:"title"`
+	Date        string   `json:"date" dynamodbav:"date"`
+	Tags        []string `json:"tags" dynamodbav:"tags"`
+	IsPublished bool     `json:"isPublished" dynamodbav:"isPublished"`
+	NoIndex     bool     `json:"noIndex,omitempty" dynamodbav:"noindex,omitempty"`
+	PublishAt   string   `json:"publishAt,omitempty" dynamodbav:"publishAt,omitempty"` // 予約公開の日時
+	ExpireAt    string   `json:"expireAt,omitempty" dynamodbav:"expireAt,omitempty"`   // 公開終了の日時
+	Pinned      bool     `json:"pinned,omitempty" dynamodbav:"pinned,omitempty"`       // 固定されている (期限切れにならない)
+	TTL         int64    `json:"ttl" dynamodbav:"ttl"`
+	Version     int      `json:"version" dynamodbav:"version"`       // 楽観的排他制御のversion (If-Matchに指定する)
+	ExpiresAt   string   `json:"expiresAt,omitempty" dynamodbav:"-"` // TTLをRFC 3339に変換したもの
+	CreatedAt   string   `json:"createdAt,omitempty" dynamodbav:"createdAt,omitempty"`
+	UpdatedAt   string   `json:"updatedAt,omitempty" dynamodbav:"updatedAt,omitempty"`
+}
+
+var dbClient *dynamodb.Client
+var draftsTableName = os.Getenv("DRAFTS_TABLE_NAME") // 下書きテーブル名
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+}
+
+// Handler handles the API Gateway proxy request to list drafts.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Println("Received request for get drafts handler.")
+
+	scheduled := request.QueryStringParameters["scheduled"] == "true"
+
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String(draftsTableName),
+		ProjectionExpression: aws.String("id, title, #date, tags, isPublished, noindex, publishAt, expireAt, pinned, #ttl, #version, createdAt, updatedAt"),
+		ExpressionAttributeNames: map[string]string{
+			"#date":    "date",
+			"#ttl":     "ttl",
+			"#version": "version",
+		},
+	}
+	if scheduled {
+		input.FilterExpression = aws.String("attribute_exists(publishAt)")
+	}
+
+	drafts := []DraftSummary{}
+	now := time.Now().Unix()
+	paginator := dynamodb.NewScanPaginator(dbClient, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			fmt.Printf("Error scanning DynamoDB table: %v\n", err)
+			return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to scan drafts: %v", err)}, nil
+		}
+		var items []DraftSummary
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			fmt.Printf("Error unmarshalling items: %v\n", err)
+			return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to unmarshal drafts: %v", err)}, nil
+		}
+		for _, item := range items {
+			// DynamoDBのTTLによる削除は即時ではないため、期限切れのアイテムは除く
+			if item.TTL > 0 && item.TTL <= now {
+				continue
+			}
+			if item.TTL > 0 {
+				item.ExpiresAt = time.Unix(item.TTL, 0).UTC().Format(time.RFC3339)
+			}
+			drafts = append(drafts, item)
+		}
+	}
+
+	if scheduled {
+		sort.SliceStable(drafts, func(i, j int) bool {
+			if drafts[i].PublishAt != drafts[j].PublishAt {
+				return drafts[i].PublishAt < drafts[j].PublishAt
+			}
+			return drafts[i].ID < drafts[j].ID
+		})
+	} else {
+		sort.SliceStable(drafts, func(i, j int) bool {
+			if drafts[i].Date != drafts[j].Date {
+				return drafts[i].Date > drafts[j].Date
+			}
+			return drafts[i].ID < drafts[j].ID
+		})
+	}
+
+	responseBody, err := json.Marshal(drafts)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "Failed to marshal response"}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(responseBody),
+	}, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/feed"
+	"github.com/sunshine-724/my-homepage-backend/internal/httpcache"
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+	"github.com/sunshine-724/my-homepage-backend/internal/site"
+)
+
+// 以下のルートで公開済み記事のフィードを返す
+//   GET /feed.xml  (RSS 2.0),  GET /atom.xml  (Atom),  GET /feed.json  (JSON Feed 1.1)
+//   GET /tags/{tag}/feed.xml,  GET /tags/{tag}/atom.xml,  GET /tags/{tag}/feed.json  (タグで絞り込み)
+
+// summaryLength: 要約の最大文字数
+const summaryLength = 200
+
+// defaultCacheControl: CACHE_CONTROL でルートのポリシーが設定されていない場合の Cache-Control
+const defaultCacheControl = "public, max-age=300"
+
+var dbClient *dynamodb.Client
+var postsTableName = os.Getenv("POSTS_TABLE_NAME") // 投稿テーブル名
+
+var feedLimit = 20                                       // FEED_LIMIT: フィードに含める記事数
+var fullContent = os.Getenv("FEED_CONTENT") != "summary" // FEED_CONTENT=summary の場合は全文を含めない
+
+var siteConfig = site.FromEnv()
+var cachePolicies = httpcache.PoliciesFromEnv()
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+
+	if v, err := strconv.Atoi(os.Getenv("FEED_LIMIT")); err == nil && v > 0 {
+		feedLimit = v
+	}
+}
+
+// Handler handles the API Gateway proxy request to get the RSS / Atom / JSON feed.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Printf("Received request for get feed handler. path=%s\n", request.Path)
+
+	route := httpcache.Route(request)
+	tag := request.PathParameters["tag"]
+
+	items, err := posts.ScanAll(ctx, dbClient, postsTableName)
+	if err != nil {
+		fmt.Printf("Error scanning DynamoDB table: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to scan posts: %v", err)}, nil
+	}
+
+	f := buildFeed(posts.Published(items), tag, siteConfig.FeedURL(path.Base(route), tag))
+
+	var body []byte
+	var contentType string
+	switch {
+	case strings.HasSuffix(route, ".json"):
+		body, err = f.JSON()
+		contentType = "application/feed+json; charset=utf-8"
+	case strings.HasSuffix(route, "atom.xml"):
+		body, err = f.Atom()
+		contentType = "application/atom+xml; charset=utf-8"
+	default:
+		body, err = f.RSS()
+		contentType = "application/rss+xml; charset=utf-8"
+	}
+	if err != nil {
+		fmt.Printf("Error marshalling feed: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "Failed to marshal feed"}, nil
+	}
+
+	// 記事のアーカイブ・公開の取り消し・公開終了ではフィードの更新日時が変わらないため、Last-Modified は返さない
+	validators := httpcache.Validators{ETag: httpcache.ETag(body)}
+	cacheControl := cachePolicies.For(route, defaultCacheControl)
+	return httpcache.Respond(request, map[string]string{"Content-Type": contentType}, string(body), validators, cacheControl), nil
+}
+
+// buildFeed は公開済みの記事 (日付の新しい順) からフィードを組み立てる
+func buildFeed(published []posts.Item, tag, feedURL string) feed.Feed {
+	f := feed.Feed{
+		Title:       siteConfig.Title,
+		Description: siteConfig.Description,
+		Link:        siteConfig.Absolute("/"),
+		FeedURL:     feedURL,
+		Language:    siteConfig.Language,
+	}
+	if tag != "" {
+		f.Title = fmt.Sprintf("%s - %s", siteConfig.Title, tag)
+		f.Link = siteConfig.TagURL(tag)
+	}
+
+	// タグ別のインデックスは持たず、公開中の記事 (posts.Published でアーカイブ・公開終了済みを除いたもの) をタグで絞り込む
+	for _, post := range published {
+		if tag != "" && !post.HasTag(tag) {
+			continue
+		}
+		if len(f.Items) >= feedLimit {
+			break
+		}
+
+		date := publishedTime(post)
+		updated := date
+		if t := httpcache.LastModified(post.UpdatedAt); t.After(updated) {
+			updated = t
+		}
+		item := feed.Item{
+			ID:         post.ID,
+			Title:      post.Title,
+			Link:       siteConfig.PostURL(post.ID, post.Slug),
+			Published:  date,
+			Updated:    updated,
+			Categories: post.Tags,
+			Summary:    feed.Summarize(post.ContentHTML, summaryLength),
+		}
+		if fullContent {
+			item.ContentHTML = post.ContentHTML
+		}
+		f.Items = append(f.Items, item)
+
+		if updated.After(f.Updated) {
+			f.Updated = updated
+		}
+	}
+
+	return f
+}
+
+// publishedTime は記事の公開日時を返す
+// date は著者が入力する自由形式の文字列なので、日付として読めない場合は最初に公開した日時 (無ければ最後に更新した日時) を使う
+func publishedTime(post posts.Item) time.Time {
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, post.Date); err == nil {
+			return t
+		}
+	}
+	if t := httpcache.LastModified(post.PublishedAt); !t.IsZero() {
+		return t
+	}
+	return httpcache.LastModified(post.UpdatedAt)
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/httpcache"
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+	"github.com/sunshine-724/my-homepage-backend/internal/site"
+	"github.com/sunshine-724/my-homepage-backend/internal/slug"
+)
+
+// RedirectBody: スラッグが変更された記事・手動リダイレクトを引いた場合のレスポンス (301)
+type RedirectBody struct {
+	Slug     string `json:"slug,omitempty"`   // 記事の現在のスラッグ
+	PostID   string `json:"postId,omitempty"` // 転送先の記事ID
+	Location string `json:"location"`         // フロントエンド向けの転送先URL (Locationヘッダーと同じ)
+}
+
+// defaultCacheControl: CACHE_CONTROL でルートのポリシーが設定されていない場合の Cache-Control (GET /posts/{id} と同じ)
+const defaultCacheControl = "no-cache"
+
+var dbClient *dynamodb.Client
+var postsTableName = os.Getenv("POSTS_TABLE_NAME")                               // 投稿テーブル名
+var slugsTableName = os.Getenv("SLUGS_TABLE_NAME")                               // スラッグのインデックス (主キーは slug)
+var dateIndexName = cmp.Or(os.Getenv("DATE_INDEX_NAME"), posts.DefaultDateIndex) // 日付のインデックス (投稿テーブルのGSI)
+
+var siteConfig = site.FromEnv()
+var cachePolicies = httpcache.PoliciesFromEnv()
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+}
+
+// Handler handles the API Gateway proxy request to get a blog post by its slug.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Println("Received request for get post by slug handler.")
+
+	// 旧ブログのURLのパスも引けるよう、前後のスラッシュは取り除く (manage-redirects と同じ正規化)
+	requested := strings.Trim(request.PathParameters["slug"], "/")
+	if requested == "" {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Invalid request Body"}, nil
+	}
+
+	// スラッグのインデックスから記事IDを引く
+	entry, err := slug.Lookup(ctx, dbClient, slugsTableName, requested)
+	if err != nil {
+		fmt.Printf("Error looking up slug %s: %v\n", requested, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "アイテムの取得に失敗しました"}, nil
+	}
+	if entry == nil {
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: "指定されたスラッグを持つアイテムは見つかりませんでした\n"}, nil
+	}
+
+	// 記事ではなくパス・URLへの手動リダイレクト
+	if entry.Kind == slug.KindManual && entry.Target != "" {
+		fmt.Printf("Redirecting %s to %s\n", requested, entry.Target)
+		return redirect(RedirectBody{Location: siteConfig.Absolute(entry.Target)}), nil
+	}
+
+	result, err := dbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(postsTableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: entry.PostID},
+		},
+	})
+	if err != nil {
+		fmt.Printf("Error getting item from DynamoDB: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "アイテムの取得に失敗しました"}, nil
+	}
+	if result.Item == nil {
+		// インデックスだけが残っている (記事が削除された) 場合
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: "指定されたスラッグを持つアイテムは見つかりませんでした\n"}, nil
+	}
+
+	var post posts.Item
+	if err := attributevalue.UnmarshalMap(result.Item, &post); err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "アイテムのパースに失敗しました"}, nil
+	}
+
+	// 公開終了の日時を過ぎた記事は、スケジューラーによるアーカイブを待たずに配信を止める
+	if post.Archived || post.Expired(time.Now()) {
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: "指定されたスラッグを持つアイテムは見つかりませんでした\n"}, nil
+	}
+
+	// 以前のスラッグ (または記事への手動リダイレクト) の場合は現在のスラッグへ転送する
+	if post.Slug != "" && post.Slug != requested {
+		fmt.Printf("Redirecting %s to current slug %s\n", requested, post.Slug)
+		return redirect(RedirectBody{
+			Slug:     post.Slug,
+			PostID:   post.ID,
+			Location: siteConfig.PostURL(post.ID, post.Slug),
+		}), nil
+	}
+
+	// 関連記事と一覧で前後にある記事を加える (GET /posts/{id} と同じ形)
+	dateIndex := posts.DateIndex{Client: dbClient, TableName: postsTableName, IndexName: dateIndexName}
+	detail, err := posts.NewDetail(ctx, dateIndex, post, time.Now())
+	if err != nil {
+		fmt.Printf("Error getting neighbors of %s: %v\n", post.ID, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "前後の記事の取得に失敗しました"}, nil
+	}
+
+	responseBody, err := json.Marshal(detail)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "レスポンスボディの作成に失敗しました"}, nil
+	}
+
+	// 関連記事と前後の記事は updatedAt を変えずに変わるため、Last-Modified は返さない (ETag だけで検証する)
+	validators := httpcache.Validators{ETag: detail.ETag()}
+	cacheControl := cachePolicies.For(httpcache.Route(request), defaultCacheControl)
+	return httpcache.Respond(request, map[string]string{"Content-Type": "application/json"}, string(responseBody), validators, cacheControl), nil
+}
+
+// redirect は301レスポンスを作る
+// API Route側でそのままリダイレクトできるよう、転送先はLocationヘッダーとボディの両方に入れる
+func redirect(body RedirectBody) events.APIGatewayProxyResponse {
+	responseBody, _ := json.Marshal(body)
+	return events.APIGatewayProxyResponse{
+		StatusCode: 301,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"Location":     body.Location,
+		},
+		Body: string(responseBody),
+	}
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -12,25 +14,24 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/httpcache"
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
 )
 
 type RequestBody struct {
 	ID string `json:"id"`
 }
 
-type PostItem struct {
-    ID          string   `json:"id" dynamodbav:"id"`
-    Title       string   `json:"title" dynamodbav:"title"`
-    Date        string   `json:"date" dynamodbav:"date"`
-    Content     string   `json:"content" dynamodbav:"content"`
-    Tags        []string `json:"tags" dynamodbav:"tags"`
-    IsPublished bool     `json:"isPublished" dynamodbav:"isPublished"`
-    TTL         int64    `json:"ttl" dynamodbav:"ttl"`
-}
-
+// defaultCacheControl: CACHE_CONTROL でルートのポリシーが設定されていない場合の Cache-Control
+// 記事の更新がすぐに反映されるよう毎回検証させる (変更が無ければ304でボディを送らない)
+const defaultCacheControl = "no-cache"
 
 var dbClient *dynamodb.Client
 var getTableName = os.Getenv("GET_TABLE_NAME")
+var dateIndexName = cmp.Or(os.Getenv("DATE_INDEX_NAME"), posts.DefaultDateIndex) // 日付のインデックス (投稿テーブルのGSI)
+
+var cachePolicies = httpcache.PoliciesFromEnv()
 
 func init() {
 	cfg, err := config.LoadDefaultConfig(context.TODO())
@@ -45,7 +46,7 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 
 	id := request.PathParameters["id"]
 
-	if(id == "") {
+	if id == "" {
 		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Invalid request Body"}, nil
 	}
 
@@ -75,26 +76,36 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		return events.APIGatewayProxyResponse{StatusCode: 404, Body: "指定された主キーを持つアイテムは見つかりませんでした\n"}, nil
 	}
 
-	var postItem PostItem
-	err = attributevalue.UnmarshalMap(result.Item, &postItem) // parse
+	var post posts.Item
+	err = attributevalue.UnmarshalMap(result.Item, &post) // parse
 	if err != nil {
 		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "アイテムのパースに失敗しました"}, nil
 	}
 
-	responseBody, err := json.Marshal(postItem)
+	// 公開終了の日時を過ぎた記事は、スケジューラーによるアーカイブを待たずに配信を止める
+	if post.Archived || post.Expired(time.Now()) {
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: "指定された主キーを持つアイテムは見つかりませんでした\n"}, nil
+	}
+
+	// 関連記事と一覧で前後にある記事を加える (GET /posts/by-slug/{slug} と同じ形)
+	dateIndex := posts.DateIndex{Client: dbClient, TableName: getTableName, IndexName: dateIndexName}
+	detail, err := posts.NewDetail(ctx, dateIndex, post, time.Now())
+	if err != nil {
+		fmt.Printf("Error getting neighbors of %s: %v\n", post.ID, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "前後の記事の取得に失敗しました"}, nil
+	}
+
+	responseBody, err := json.Marshal(detail)
 	if err != nil {
 		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "レスポンスボディの作成に失敗しました"}, nil
 	}
 
 	fmt.Println("Response Body: " + string(responseBody))
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(responseBody),
-	}, nil
+	// 関連記事と前後の記事は updatedAt を変えずに変わるため、Last-Modified は返さない (ETag だけで検証する)
+	validators := httpcache.Validators{ETag: detail.ETag()}
+	cacheControl := cachePolicies.For(httpcache.Route(request), defaultCacheControl)
+	return httpcache.Respond(request, map[string]string{"Content-Type": "application/json"}, string(responseBody), validators, cacheControl), nil
 }
 
 func main() {
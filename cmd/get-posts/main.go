@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -12,23 +14,36 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/httpcache"
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+	"github.com/sunshine-724/my-homepage-backend/internal/projection"
 )
 
-// PostItem: DynamoDBのブログ投稿テーブルから取得するデータ構造
-// TTLフィールドはblog_postsテーブルには通常ないため、定義しないか、あっても無視される
-type PostItem struct {
-    ID          string   `json:"id" dynamodbav:"id"`
-    Title       string   `json:"title" dynamodbav:"title"`
-    Date        string   `json:"date" dynamodbav:"date"`
-    Content     string   `json:"content" dynamodbav:"content"`
-    Tags        []string `json:"tags" dynamodbav:"tags"`
-    IsPublished bool     `json:"isPublished" dynamodbav:"isPublished"`
-    TTL         int64    `json:"ttl" dynamodbav:"ttl"`
+// listFields: ?fields= で指定できるフィールド (posts.Item のJSONのキー)
+var listFields = []string{
+	"id", "title", "slug", "date", "content", "contentHtml", "tags", "isPublished", "archived", "expireAt", "ttl",
+	"createdAt", "updatedAt", "publishedAt", "version", "charCount", "wordCount", "readingTime", "excerpt",
+}
+
+// summaryFields: view=summary の場合に返すフィールド (本文の代わりに要約と読了時間を返す)
+var summaryFields = []string{
+	"id", "title", "slug", "date", "tags", "isPublished", "createdAt", "updatedAt", "publishedAt", "version",
+	"charCount", "wordCount", "readingTime", "excerpt",
 }
 
+// filterAttributes: 指定されたフィールドに関わらず読み出す属性 (一覧からの除外に使う)
+var filterAttributes = []string{"archived", "expireAt"}
+
+// defaultCacheControl: CACHE_CONTROL でルートのポリシーが設定されていない場合の Cache-Control
+// 記事の更新がすぐに反映されるよう毎回検証させる (変更が無ければ304でボディを送らない)
+const defaultCacheControl = "no-cache"
+
 var dbClient *dynamodb.Client
 var postsTableName = os.Getenv("POSTS_TABLE_NAME") // 投稿テーブル名
 
+var cachePolicies = httpcache.PoliciesFromEnv()
+
 func init() {
 	cfg, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
@@ -41,10 +56,39 @@ func init() {
 func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	fmt.Println("Received request for get posts handler.")
 
+	// ?view=summary と ?fields=id,title,... で返すフィールドを絞る
+	var fields projection.Fields
+	switch view := request.QueryStringParameters["view"]; view {
+	case "", "full":
+	case "summary":
+		fields = summaryFields
+	default:
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Invalid view %q (allowed: full, summary)", view)}, nil
+	}
+	if value := request.QueryStringParameters["fields"]; value != "" {
+		// view=summary と併用した場合は、要約のフィールドだけを指定できる (それ以外のフィールドは400)
+		allowed := listFields
+		if fields != nil {
+			allowed = summaryFields
+		}
+		requested, err := projection.Parse(value, allowed)
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Invalid fields: %v", err)}, nil
+		}
+		if len(requested) > 0 {
+			fields = requested
+		}
+	}
+
 	// ScanInputを作成し、DynamoDBテーブルをスキャン
 	scanInput := &dynamodb.ScanInput{
 		TableName: aws.String(postsTableName),
 	}
+	if fields != nil {
+		// 指定されたフィールドだけを読み出す (要約と読了時間は公開時に計算して保存してある)
+		attributes := append(slices.Clone(fields), filterAttributes...)
+		scanInput.ProjectionExpression, scanInput.ExpressionAttributeNames = projection.Expression(attributes...)
+	}
 
 	// DynamoDBから全アイテムを取得
 	// Scan操作はテーブルサイズが大きくなるとパフォーマンスに影響するため、
@@ -55,16 +99,42 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to scan posts: %v", err)}, nil
 	}
 
-	// 取得したアイテムをGoのPostItem構造体のスライスに変換
-	var posts []PostItem
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &posts)
+	// 取得したアイテムをGoのposts.Item構造体のスライスに変換
+	var items []posts.Item
+	err = attributevalue.UnmarshalListOfMaps(result.Items, &items)
 	if err != nil {
 		fmt.Printf("Error unmarshalling items: %v\n", err)
 		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to unmarshal posts: %v", err)}, nil
 	}
 
-	// レスポンスボディをJSONに変換
-	responseBody, err := json.Marshal(posts)
+	// アーカイブ済みの記事と、公開終了の日時を過ぎた (まだアーカイブされていない) 記事は除く
+	now := time.Now()
+	postItems := []posts.Item{}
+	for _, item := range items {
+		if item.Archived || posts.Expired(item.ExpireAt, now) {
+			continue
+		}
+		if item.Related == nil {
+			item.Related = []posts.Related{}
+		}
+		postItems = append(postItems, item)
+	}
+
+	// レスポンスボディをJSONに変換 (フィールドが指定された場合は、指定されたキーだけを返す)
+	var response any = postItems
+	if fields != nil {
+		selected := make([]map[string]any, 0, len(postItems))
+		for _, item := range postItems {
+			m, err := projection.Select(item, fields)
+			if err != nil {
+				fmt.Printf("Error selecting fields: %v\n", err)
+				return events.APIGatewayProxyResponse{StatusCode: 500, Body: "Failed to marshal response"}, nil
+			}
+			selected = append(selected, m)
+		}
+		response = selected
+	}
+	responseBody, err := json.Marshal(response)
 	if err != nil {
 		fmt.Printf("Error marshalling response body: %v\n", err)
 		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "Failed to marshal response"}, nil
@@ -72,12 +142,11 @@ func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 
 	fmt.Printf("responseBody: %v\n", responseBody)
 
-	// 成功レスポンスを返す
-	return events.APIGatewayProxyResponse{
-		StatusCode: 200,
-		Headers:    map[string]string{"Content-Type": "application/json"},
-		Body:       string(responseBody),
-	}, nil
+	// 成功レスポンスを返す (一覧のETagはレスポンスボディのハッシュ。記事の追加・削除でも変わる)
+	// 記事のアーカイブ・公開の取り消し・公開終了では updatedAt の最大値が変わらないため、Last-Modified は返さない
+	validators := httpcache.Validators{ETag: httpcache.ETag(responseBody)}
+	cacheControl := cachePolicies.For(httpcache.Route(request), defaultCacheControl)
+	return httpcache.Respond(request, map[string]string{"Content-Type": "application/json"}, string(responseBody), validators, cacheControl), nil
 }
 
 func main() {
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/revision"
+)
+
+// 記事 (下書き) のリビジョン履歴を返す
+//   GET /posts/{id}/revisions                          リビジョンの一覧 (新しい順、本文は含まない)
+//   GET /posts/{id}/revisions/{rev}                    リビジョン1件 (本文を含む)
+//   GET /posts/{id}/revisions/{rev}/diff?against={n}   2つのリビジョンの行単位の差分 (againstの省略時は直前のリビジョン)
+
+// DiffResponse: 差分のレスポンス
+type DiffResponse struct {
+	PostID string          `json:"postId"`
+	From   int             `json:"from"` // 変更前のリビジョン
+	To     int             `json:"to"`   // 変更後のリビジョン
+	Title  []revision.Line `json:"title"`
+	Lines  []revision.Line `json:"lines"` // 本文の差分
+}
+
+var dbClient *dynamodb.Client
+var revisionsTableName = os.Getenv("REVISIONS_TABLE_NAME") // リビジョン (履歴) のテーブル名
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+}
+
+// Handler handles the API Gateway proxy request to get revisions of a post.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Printf("Received request for get revisions handler. path=%s\n", request.Path)
+
+	id := request.PathParameters["id"]
+	if id == "" {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Missing post ID"}, nil
+	}
+
+	revParam, ok := request.PathParameters["rev"]
+	if !ok || revParam == "" {
+		return listRevisions(ctx, id)
+	}
+
+	rev, err := strconv.Atoi(revParam)
+	if err != nil || rev < 1 {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Invalid revision: %s", revParam)}, nil
+	}
+
+	if request.Resource == "/posts/{id}/revisions/{rev}/diff" {
+		return diffRevisions(ctx, id, rev, request.QueryStringParameters["against"])
+	}
+	return getRevision(ctx, id, rev)
+}
+
+func listRevisions(ctx context.Context, id string) (events.APIGatewayProxyResponse, error) {
+	revisions, err := revision.List(ctx, dbClient, revisionsTableName, id)
+	if err != nil {
+		fmt.Printf("Error listing revisions of %s: %v\n", id, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to list revisions: %v", err)}, nil
+	}
+	if len(revisions) == 0 {
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Revisions of %s not found", id)}, nil
+	}
+	return jsonResponse(200, revisions), nil
+}
+
+func getRevision(ctx context.Context, id string, rev int) (events.APIGatewayProxyResponse, error) {
+	r, err := revision.Get(ctx, dbClient, revisionsTableName, id, rev)
+	if err != nil {
+		fmt.Printf("Error getting revision %s@%d: %v\n", id, rev, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get revision: %v", err)}, nil
+	}
+	if r == nil {
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Revision %d of %s not found", rev, id)}, nil
+	}
+	return jsonResponse(200, r), nil
+}
+
+func diffRevisions(ctx context.Context, id string, rev int, against string) (events.APIGatewayProxyResponse, error) {
+	from := rev - 1
+	if against != "" {
+		n, err := strconv.Atoi(against)
+		if err != nil || n < 1 {
+			return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Invalid revision: %s", against)}, nil
+		}
+		from = n
+	}
+	if from < 1 {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Revision 1 has no previous revision; specify against"}, nil
+	}
+
+	// 古い方を変更前として扱う
+	to := rev
+	if from > to {
+		from, to = to, from
+	}
+
+	var pair [2]*revision.Revision
+	for i, n := range []int{from, to} {
+		r, err := revision.Get(ctx, dbClient, revisionsTableName, id, n)
+		if err != nil {
+			fmt.Printf("Error getting revision %s@%d: %v\n", id, n, err)
+			return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get revision: %v", err)}, nil
+		}
+		if r == nil {
+			return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Revision %d of %s not found", n, id)}, nil
+		}
+		pair[i] = r
+	}
+
+	return jsonResponse(200, DiffResponse{
+		PostID: id,
+		From:   from,
+		To:     to,
+		Title:  revision.Diff(pair[0].Title, pair[1].Title),
+		Lines:  revision.Diff(pair[0].Content, pair[1].Content),
+	}), nil
+}
+
+func jsonResponse(statusCode int, body any) events.APIGatewayProxyResponse {
+	responseBody, err := json.Marshal(body)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "Failed to marshal response"}
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(responseBody),
+	}
+}
+
+func main() {
+	lambda.Start(Handler)
+}
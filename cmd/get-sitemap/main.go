@@ -0,0 +1,151 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+	"github.com/sunshine-724/my-homepage-backend/internal/site"
+	"github.com/sunshine-724/my-homepage-backend/internal/sitemap"
+)
+
+// 以下のルートで検索エンジン向けのファイルを返す
+//   GET /sitemap.xml         記事数が上限以下なら urlset、超える場合は sitemap index
+//   GET /sitemaps/{page}     sitemap index から参照される分割済みの urlset (page は 1 始まり、ex. /sitemaps/1.xml)
+//   GET /robots.txt          sitemap.xml を参照する robots.txt
+
+var dbClient *dynamodb.Client
+var postsTableName = os.Getenv("POSTS_TABLE_NAME") // 投稿テーブル名
+
+var urlsPerSitemap = sitemap.MaxURLs              // SITEMAP_MAX_URLS: 1つのsitemapに含めるURL数
+var robotsDisallow = os.Getenv("ROBOTS_DISALLOW") // robots.txt で Disallow するパス (カンマ区切り)
+
+var siteConfig = site.FromEnv()
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+
+	if v, err := strconv.Atoi(os.Getenv("SITEMAP_MAX_URLS")); err == nil && v > 0 {
+		urlsPerSitemap = min(v, sitemap.MaxURLs)
+	}
+}
+
+// Handler handles the API Gateway proxy request to get sitemap.xml and robots.txt.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Printf("Received request for get sitemap handler. path=%s\n", request.Path)
+
+	route := request.Resource
+	if route == "" {
+		route = request.Path
+	}
+
+	if strings.HasSuffix(route, "robots.txt") {
+		return textResponse(200, "text/plain; charset=utf-8", robotsTxt()), nil
+	}
+
+	items, err := posts.ScanAll(ctx, dbClient, postsTableName)
+	if err != nil {
+		fmt.Printf("Error scanning DynamoDB table: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to scan posts: %v", err)}, nil
+	}
+	pages := sitemap.Pages(postURLs(posts.Published(items)), urlsPerSitemap)
+
+	var body []byte
+	if page := request.PathParameters["page"]; page != "" {
+		n, convErr := strconv.Atoi(strings.TrimSuffix(page, ".xml"))
+		if convErr != nil || n < 1 || n > len(pages) {
+			return events.APIGatewayProxyResponse{StatusCode: 404, Body: "Sitemap not found"}, nil
+		}
+		body, err = sitemap.URLSet(pages[n-1])
+	} else if len(pages) <= 1 {
+		var urls []sitemap.URL
+		if len(pages) == 1 {
+			urls = pages[0]
+		}
+		body, err = sitemap.URLSet(urls)
+	} else {
+		body, err = sitemap.Index(indexRefs(pages))
+	}
+	if err != nil {
+		fmt.Printf("Error marshalling sitemap: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "Failed to marshal sitemap"}, nil
+	}
+
+	return textResponse(200, "application/xml; charset=utf-8", string(body)), nil
+}
+
+// postURLs は公開済みの記事のうち、検索エンジンに公開してよいもののURLを返す
+func postURLs(published []posts.Item) []sitemap.URL {
+	var urls []sitemap.URL
+	for _, post := range published {
+		if post.NoIndex || post.Archived {
+			continue
+		}
+		// lastmod は記事を最後に更新した日時 (date は著者が入力する自由形式の表示用の日付なので使わない)
+		urls = append(urls, sitemap.URL{
+			Loc:     siteConfig.PostURL(post.ID, post.Slug),
+			LastMod: cmp.Or(post.UpdatedAt, post.PublishedAt),
+		})
+	}
+	return urls
+}
+
+// indexRefs は分割したsitemapを参照する sitemap index のエントリーを作る
+// lastmod には各ページに含まれる記事の最新の更新日時を使う (どれもUTCのRFC 3339なので文字列で比べられる)
+func indexRefs(pages [][]sitemap.URL) []sitemap.Ref {
+	refs := make([]sitemap.Ref, 0, len(pages))
+	for i, page := range pages {
+		ref := sitemap.Ref{Loc: siteConfig.Absolute(fmt.Sprintf("/sitemaps/%d.xml", i+1))}
+		for _, u := range page {
+			if u.LastMod > ref.LastMod {
+				ref.LastMod = u.LastMod
+			}
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// robotsTxt は robots.txt の本文を作る
+func robotsTxt() string {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	if robotsDisallow == "" {
+		b.WriteString("Allow: /\n")
+	}
+	for _, path := range strings.Split(robotsDisallow, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			b.WriteString("Disallow: " + path + "\n")
+		}
+	}
+	b.WriteString("\nSitemap: " + siteConfig.Absolute("/sitemap.xml") + "\n")
+	return b.String()
+}
+
+func textResponse(statusCode int, contentType, body string) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type":  contentType,
+			"Cache-Control": "public, max-age=3600",
+		},
+		Body: body,
+	}
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/drafts"
+	"github.com/sunshine-724/my-homepage-backend/internal/publish"
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
+)
+
+// 下書きの固定 (期限切れで削除されないようにする)
+//   POST   /drafts/{id}/keep   下書きを固定する (TTLを削除する)
+//   DELETE /drafts/{id}/keep   固定を解除する (現在時刻から保持期間 DRAFT_TTL 後をTTLに設定する)
+// どちらも下書きの更新として If-Match ヘッダーに version を指定する
+
+var dbClient *dynamodb.Client
+var draftsTableName = os.Getenv("DRAFTS_TABLE_NAME") // 下書きテーブル名
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+}
+
+// Handler handles the API Gateway proxy request to pin or unpin a draft.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Printf("Received request for keep draft handler. method=%s\n", request.HTTPMethod)
+
+	id := request.PathParameters["id"]
+	if id == "" {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Missing draft ID"}, nil
+	}
+	if request.HTTPMethod != "POST" && request.HTTPMethod != "DELETE" {
+		return events.APIGatewayProxyResponse{StatusCode: 405, Body: "Method not allowed"}, nil
+	}
+
+	expected, err := version.IfMatch(request)
+	if response, ok := version.ErrorResponse(err); ok {
+		return response, nil
+	}
+
+	// 予約公開の日時より前に期限切れにならないよう、固定の解除ではpublishAtも確認する
+	result, err := dbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(draftsTableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ProjectionExpression:     aws.String("publishAt, #version"),
+		ExpressionAttributeNames: map[string]string{"#version": "version"},
+	})
+	if err != nil {
+		fmt.Printf("Error getting draft %s: %v\n", id, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get draft: %v", err)}, nil
+	}
+	if result.Item == nil {
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Draft with ID %s not found", id)}, nil
+	}
+	var current struct {
+		PublishAt string `dynamodbav:"publishAt"`
+		Version   int    `dynamodbav:"version"`
+	}
+	if err := attributevalue.UnmarshalMap(result.Item, &current); err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to unmarshal draft: %v", err)}, nil
+	}
+	if response, ok := version.ErrorResponse(version.Check(expected, current.Version)); ok {
+		return response, nil
+	}
+
+	body := map[string]any{"id": id, "version": current.Version + 1}
+	action := "keep"
+	if request.HTTPMethod == "POST" {
+		err = drafts.Keep(ctx, dbClient, draftsTableName, id, current.Version)
+		body["pinned"] = true
+	} else {
+		action = "unkeep"
+		ttl := publish.ScheduledTTL(drafts.TTL(time.Now()), current.PublishAt)
+		err = drafts.Unkeep(ctx, dbClient, draftsTableName, id, current.Version, ttl)
+		body["pinned"] = false
+		body["ttl"] = ttl
+		body["expiresAt"] = time.Unix(ttl, 0).UTC().Format(time.RFC3339)
+	}
+	if version.ConditionFailed(err, 0) {
+		mismatch, err := version.Conflict(ctx, dbClient, draftsTableName, id)
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get draft: %v", err)}, nil
+		}
+		if mismatch == nil {
+			return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Draft with ID %s not found", id)}, nil
+		}
+		response, _ := version.ErrorResponse(mismatch)
+		return response, nil
+	}
+	if err != nil {
+		fmt.Printf("Error %sing draft %s: %v\n", action, id, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to %s draft: %v", action, err)}, nil
+	}
+	fmt.Printf("Updated pin of draft %s: %v (version %d)\n", id, body["pinned"], current.Version+1)
+
+	response := jsonResponse(200, body)
+	response.Headers["ETag"] = version.ETag(current.Version + 1)
+	return response, nil
+}
+
+func jsonResponse(statusCode int, body any) events.APIGatewayProxyResponse {
+	responseBody, err := json.Marshal(body)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "Failed to marshal response"}
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(responseBody),
+	}
+}
+
+func main() {
+	lambda.Start(Handler)
+}
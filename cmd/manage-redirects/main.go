@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/slug"
+)
+
+// 管理画面からリダイレクトを操作する
+//   GET    /redirects          リダイレクト (以前のスラッグと手動登録) の一覧
+//   PUT    /redirects/{slug}   手動リダイレクトの登録・更新 (旧ブログのURLなど)
+//   DELETE /redirects/{slug}   リダイレクトの削除
+
+// RequestBody: PUT /redirects/{slug} のリクエストボディ
+// postId と target のどちらか一方を指定する
+type RequestBody struct {
+	PostID string `json:"postId"` // 転送先の記事 (記事の現在のスラッグへ転送する)
+	Target string `json:"target"` // 転送先のサイト内のパスまたはURL
+}
+
+var dbClient *dynamodb.Client
+var postsTableName = os.Getenv("POSTS_TABLE_NAME") // 投稿テーブル名
+var slugsTableName = os.Getenv("SLUGS_TABLE_NAME") // スラッグのインデックス (主キーは slug)
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+}
+
+// Handler handles the API Gateway proxy request to list and edit redirects.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Printf("Received request for manage redirects handler. method=%s\n", request.HTTPMethod)
+
+	switch request.HTTPMethod {
+	case "GET":
+		return listRedirects(ctx)
+	case "PUT":
+		return putRedirect(ctx, request)
+	case "DELETE":
+		return deleteRedirect(ctx, request)
+	default:
+		return events.APIGatewayProxyResponse{StatusCode: 405, Body: "Method not allowed"}, nil
+	}
+}
+
+func listRedirects(ctx context.Context) (events.APIGatewayProxyResponse, error) {
+	entries, err := slug.ListRedirects(ctx, dbClient, slugsTableName)
+	if err != nil {
+		fmt.Printf("Error listing redirects: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to list redirects: %v", err)}, nil
+	}
+	if entries == nil {
+		entries = []slug.Entry{}
+	}
+	return jsonResponse(200, entries), nil
+}
+
+func putRedirect(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	from := redirectKey(request.PathParameters["slug"])
+	if from == "" {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Missing slug"}, nil
+	}
+
+	var reqBody RequestBody
+	if err := json.Unmarshal([]byte(request.Body), &reqBody); err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Invalid request body"}, nil
+	}
+	if (reqBody.PostID == "") == (reqBody.Target == "") {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Either postId or target must be specified"}, nil
+	}
+
+	if reqBody.PostID != "" {
+		exists, err := postExists(ctx, reqBody.PostID)
+		if err != nil {
+			fmt.Printf("Error getting post %s: %v\n", reqBody.PostID, err)
+			return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get post: %v", err)}, nil
+		}
+		if !exists {
+			return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Post with ID %s not found", reqBody.PostID)}, nil
+		}
+	}
+
+	entry, err := slug.PutRedirect(ctx, dbClient, slugsTableName, slug.Entry{
+		Slug:   from,
+		PostID: reqBody.PostID,
+		Target: reqBody.Target,
+	})
+	if errors.Is(err, slug.ErrCurrentSlug) {
+		return events.APIGatewayProxyResponse{StatusCode: 409, Body: fmt.Sprintf("Slug %s is the current slug of a post", from)}, nil
+	}
+	if err != nil {
+		fmt.Printf("Error putting redirect %s: %v\n", from, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to put redirect: %v", err)}, nil
+	}
+
+	return jsonResponse(200, entry), nil
+}
+
+func deleteRedirect(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	from := redirectKey(request.PathParameters["slug"])
+	if from == "" {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Missing slug"}, nil
+	}
+
+	err := slug.DeleteRedirect(ctx, dbClient, slugsTableName, from)
+	if errors.Is(err, slug.ErrNotRedirect) {
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Redirect %s not found", from)}, nil
+	}
+	if err != nil {
+		fmt.Printf("Error deleting redirect %s: %v\n", from, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to delete redirect: %v", err)}, nil
+	}
+
+	return jsonResponse(200, map[string]string{"message": fmt.Sprintf("Redirect %s deleted successfully", from)}), nil
+}
+
+// redirectKey は旧ブログのURLのパスなども登録できるよう、検証せずに正規化 (slug.Key) だけしてキーにする
+// 読み出し (slug.Lookup) でも同じ正規化をするため、大文字・全角で登録・アクセスしても一致する
+func redirectKey(s string) string {
+	return slug.Key(s)
+}
+
+func postExists(ctx context.Context, id string) (bool, error) {
+	result, err := dbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(postsTableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ProjectionExpression: aws.String("id"),
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.Item != nil, nil
+}
+
+func jsonResponse(statusCode int, body any) events.APIGatewayProxyResponse {
+	responseBody, err := json.Marshal(body)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "Failed to marshal response"}
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(responseBody),
+	}
+}
+
+func main() {
+	lambda.Start(Handler)
+}
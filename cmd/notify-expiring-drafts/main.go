@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/clock"
+	"github.com/sunshine-724/my-homepage-backend/internal/drafts"
+	"github.com/sunshine-724/my-homepage-backend/internal/notify"
+)
+
+// EventBridge のスケジュール (ex. cron(0 0 * * ? *)) から1日1回起動し、まもなく期限切れになる下書きを通知する
+// 通知を受けたら、下書きを更新する (PUT /drafts/{id}) か固定する (POST /drafts/{id}/keep) ことで削除を防げる
+
+// warningWindow: 通知の対象にする期限切れまでの時間
+const warningWindow = 48 * time.Hour
+
+// Report: 実行結果
+type Report struct {
+	Now      string            `json:"now"`
+	Expiring []drafts.Expiring `json:"expiring"`
+	Notified bool              `json:"notified"`
+}
+
+var dbClient *dynamodb.Client
+var draftsTableName = os.Getenv("DRAFTS_TABLE_NAME") // 下書きテーブル名
+
+var notifier notify.Notifier
+var currentClock clock.Clock = clock.System // 期限の判定に使う時計
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+
+	notifier, err = notify.FromEnv()
+	if err != nil {
+		// 設定の誤りで通知が届かなくなるよりは、ログに出力する
+		fmt.Fprintf(os.Stderr, "Error creating notifier, falling back to log: %v\n", err)
+		notifier = notify.Log{}
+	}
+}
+
+// Handler handles EventBridge scheduled events.
+func Handler(ctx context.Context, event events.CloudWatchEvent) (Report, error) {
+	fmt.Printf("Received scheduled event %s for notify expiring drafts handler.\n", event.ID)
+
+	t := currentClock.Now()
+	report := Report{Now: t.UTC().Format(time.RFC3339), Expiring: []drafts.Expiring{}}
+
+	expiring, err := drafts.ExpiringWithin(ctx, dbClient, draftsTableName, t, warningWindow)
+	if err != nil {
+		return report, fmt.Errorf("failed to find expiring drafts: %w", err)
+	}
+	fmt.Printf("Found %d draft(s) expiring within %s\n", len(expiring), warningWindow)
+	if len(expiring) == 0 {
+		return report, nil
+	}
+	report.Expiring = expiring
+
+	if err := notifier.Notify(ctx, message(expiring)); err != nil {
+		return report, fmt.Errorf("failed to notify: %w", err)
+	}
+	report.Notified = true
+
+	return report, nil
+}
+
+// message は期限切れが近い下書きの一覧から通知を作る
+func message(expiring []drafts.Expiring) notify.Message {
+	var text strings.Builder
+	for _, draft := range expiring {
+		title := draft.Title
+		if title == "" {
+			title = "(無題)"
+		}
+		fmt.Fprintf(&text, "- %s (%s): %s に削除されます\n", title, draft.ID, draft.ExpiresAt)
+	}
+	text.WriteString("残す場合は下書きを更新するか、POST /drafts/{id}/keep で固定してください。")
+
+	return notify.Message{
+		Subject: fmt.Sprintf("%d件の下書きが%d時間以内に期限切れになります", len(expiring), int(warningWindow.Hours())),
+		Text:    text.String(),
+		Payload: expiring,
+	}
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -3,16 +3,22 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/idempotency"
+	"github.com/sunshine-724/my-homepage-backend/internal/publish"
+	"github.com/sunshine-724/my-homepage-backend/internal/revision"
+	"github.com/sunshine-724/my-homepage-backend/internal/search"
+	"github.com/sunshine-724/my-homepage-backend/internal/slug"
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
 )
 
 // RequestBody: フロントエンドから送られてくるリクエストボディ
@@ -20,22 +26,19 @@ import (
 type RequestBody struct {
 	ID          string `json:"id"`
 	IsPublished bool   `json:"isPublished"`
-}
-
-// DraftItem: DynamoDBの下書きテーブルと公開用テーブルで共有するデータ構造
-type DraftItem struct {
-	ID          string    `dynamodbav:"id"`
-	Title       string    `dynamodbav:"title"`
-	Date			string    `dynamodbav:"date"`
-	Content     string    `dynamodbav:"content"`
-	Tags        []string  `dynamodbav:"tags"`
-	IsPublished bool      `dynamodbav:"isPublished"`
-	TTL         int64     `dynamodbav:"ttl"` // 下書きテーブルでのみ有効
+	Slug        string `json:"slug"` // 省略した場合は公開済みのスラッグを引き継ぐか、タイトルから生成する
 }
 
 var dbClient *dynamodb.Client
 var draftsTableName = os.Getenv("DRAFTS_TABLE_NAME") // 下書きテーブル名
 var postsTableName = os.Getenv("POSTS_TABLE_NAME")   // 投稿テーブル名
+var slugsTableName = os.Getenv("SLUGS_TABLE_NAME")   // スラッグのインデックス (主キーは slug)
+var revisionsTableName = os.Getenv("REVISIONS_TABLE_NAME") // リビジョン (履歴) のテーブル名
+
+var attachmentBaseURL = os.Getenv("ATTACHMENT_BASE_URL") // 添付ファイルの公開URLのベース (CDNなど、必須)
+
+var publisher *publish.Publisher
+var idempotencyStore *idempotency.Store // Idempotency-Key の記録 (IDEMPOTENCY_TABLE_NAME が未設定の場合は使わない)
 
 func init() {
 	cfg, err := config.LoadDefaultConfig(context.TODO())
@@ -45,87 +48,84 @@ func init() {
 		// 本番環境では、アプリケーションの起動に失敗した場合の適切なハンドリングを検討
 	}
 	dbClient = dynamodb.NewFromConfig(cfg)
+
+	publisher = &publish.Publisher{
+		Client:            dbClient,
+		DraftsTable:       draftsTableName,
+		PostsTable:        postsTableName,
+		SlugsTable:        slugsTableName,
+		RevisionsTable:    revisionsTableName,
+		AttachmentBaseURL: publish.AttachmentBaseURL(attachmentBaseURL),
+		Search:            search.StoreFromEnv(s3.NewFromConfig(cfg)),
+	}
+	idempotencyStore = &idempotency.Store{Client: dbClient, Table: os.Getenv("IDEMPOTENCY_TABLE_NAME")}
+	if idempotencyStore.Table == "" {
+		fmt.Fprintln(os.Stderr, "Warning: IDEMPOTENCY_TABLE_NAME is not set; Idempotency-Key headers will not prevent duplicate requests")
+	}
 }
 
+// Handler は Idempotency-Key 付きの再試行に最初の公開のレスポンスを返す
+// (公開後は下書きが削除されているため、再試行をそのまま処理すると404になる)
 func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return idempotencyStore.Do(ctx, "publish-post", request, func() (events.APIGatewayProxyResponse, error) {
+		return publishPost(ctx, request)
+	})
+}
+
+func publishPost(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 
 	fmt.Println("Received request for post handler.")
 	fmt.Printf("Request Body is %v\n",request.Body)
 
+	// If-Match には公開する下書きのversionを指定する
+	draftVersion, err := version.IfMatch(request)
+	if response, ok := version.ErrorResponse(err); ok {
+		return response, nil
+	}
+
 	var reqBody RequestBody
-	err := json.Unmarshal([]byte(request.Body), &reqBody)
+	err = json.Unmarshal([]byte(request.Body), &reqBody)
 	if err != nil {
 		fmt.Printf("Error unmarshalling request body: %v\n", err)
 		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Invalid request body"}, nil
 	}
 
-	// 1. blog_drafts テーブルから下書きデータを取得
-	getItemInput := &dynamodb.GetItemInput{
-		TableName: aws.String(draftsTableName),
-		Key: map[string]types.AttributeValue{
-			"id": &types.AttributeValueMemberS{Value: reqBody.ID},
-		},
-	}
-	fmt.Printf("Getting item from drafts table: %s\n", reqBody.ID)
-	result, err := dbClient.GetItem(ctx, getItemInput)
-	if err != nil {
-		fmt.Printf("Error getting item from DynamoDB: %v\n", err)
-		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get draft: %v", err)}, nil
-	}
-
-	if result.Item == nil {
+	result, err := publisher.Publish(ctx, publish.Request{
+		ID:           reqBody.ID,
+		IsPublished:  reqBody.IsPublished,
+		Slug:         reqBody.Slug,
+		Author:       revision.AuthorFromRequest(request),
+		DraftVersion: draftVersion,
+	})
+	if errors.Is(err, publish.ErrDraftNotFound) {
 		fmt.Printf("Draft not found with ID: %s\n", reqBody.ID)
 		return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Draft with ID %s not found", reqBody.ID)}, nil
 	}
-
-	var draftItem DraftItem
-	err = attributevalue.UnmarshalMap(result.Item, &draftItem)
-	if err != nil {
-		fmt.Printf("Error unmarshalling item: %v\n", err)
-		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to unmarshal draft: %v", err)}, nil
-	}
-
-	// 2. 公開フラグを更新
-	draftItem.IsPublished = reqBody.IsPublished
-	// 投稿時はTTLをなくすか、別の期間を設定する（blog_postsテーブルにはTTLは通常不要）
-	draftItem.TTL = 0 // blog_postsテーブルにTTLは設定しないので0にするか、フィールド自体を削除
-
-	// 3. blog_posts テーブルにデータを保存
-	av, err := attributevalue.MarshalMap(draftItem)
-	if err != nil {
-		fmt.Printf("Error marshalling post item: %v\n", err)
-		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to marshal post: %v", err)}, nil
+	if response, ok := version.ErrorResponse(err); ok {
+		return response, nil
 	}
-
-	putItemInput := &dynamodb.PutItemInput{
-		Item:      av,
-		TableName: aws.String(postsTableName),
+	if errors.Is(err, publish.ErrPostModified) {
+		return events.APIGatewayProxyResponse{StatusCode: 409, Body: fmt.Sprintf("Post with ID %s was modified by another request", reqBody.ID)}, nil
 	}
-	fmt.Printf("Putting item to posts table: %s\n", draftItem.ID)
-	_, err = dbClient.PutItem(ctx, putItemInput)
-	if err != nil {
-		fmt.Printf("Error putting item to posts table: %v\n", err)
-		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to put post to DynamoDB: %v", err)}, nil
+	if errors.Is(err, slug.ErrInvalid) {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Invalid slug: %v", errors.Unwrap(err))}, nil
 	}
-
-	// 4. blog_drafts テーブルから下書きを削除
-	deleteItemInput := &dynamodb.DeleteItemInput{
-		TableName: aws.String(draftsTableName),
-		Key: map[string]types.AttributeValue{
-			"id": &types.AttributeValueMemberS{Value: reqBody.ID},
-		},
+	if errors.Is(err, slug.ErrTaken) {
+		return events.APIGatewayProxyResponse{StatusCode: 409, Body: fmt.Sprintf("Slug %s is already used by another post", reqBody.Slug)}, nil
 	}
-	fmt.Printf("Deleting item from drafts table: %s\n", reqBody.ID)
-	_, err = dbClient.DeleteItem(ctx, deleteItemInput)
 	if err != nil {
-		// 下書きの削除が失敗しても、投稿自体は成功しているので、ここではエラーを返さない（ログは出す）
-		fmt.Printf("Error deleting item from drafts table: %v\n", err)
+		fmt.Printf("Error publishing %s: %v\n", reqBody.ID, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to publish: %v", err)}, nil
 	}
 
-	responseBody, _ := json.Marshal(map[string]string{"message": "Blog post published successfully!", "id": draftItem.ID})
+	response := map[string]any{"message": "Blog post published successfully!", "id": result.ID, "slug": result.Slug, "revision": result.Revision, "version": result.Version, "publishedAt": result.PublishedAt}
+	if len(result.Warnings) > 0 {
+		response["warnings"] = result.Warnings
+	}
+	responseBody, _ := json.Marshal(response)
 	return events.APIGatewayProxyResponse{
 		StatusCode: 200,
-		Headers:    map[string]string{"Content-Type": "application/json"},
+		Headers:    map[string]string{"Content-Type": "application/json", "ETag": version.ETag(result.Version)},
 		Body:       string(responseBody),
 	}, nil
 }
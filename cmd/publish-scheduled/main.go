@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/clock"
+	"github.com/sunshine-724/my-homepage-backend/internal/publish"
+	"github.com/sunshine-724/my-homepage-backend/internal/search"
+)
+
+// EventBridge のスケジュール (ex. rate(5 minutes)) から起動し、予約公開の日時を過ぎた下書きを公開する
+// あわせて公開終了の日時 (expireAt) を過ぎた記事をアーカイブし、一覧・タグ別フィード・フィード・サイトマップから外す
+// タグ別フィードはタグのインデックスを持たず配信時に絞り込んでいるため、archived を立てるだけで外れる (アーカイブまでの間も posts.Expired で除外される)
+// 公開処理は POST /posts (publish-post) と同じ publish.Publisher を使う
+// 公開に失敗した下書きはそのまま残り、次回の実行で再試行される (TTLは予約公開の日時 + publish.ScheduleGrace まで延ばしてある)
+
+var dbClient *dynamodb.Client
+var draftsTableName = os.Getenv("DRAFTS_TABLE_NAME")       // 下書きテーブル名
+var postsTableName = os.Getenv("POSTS_TABLE_NAME")         // 投稿テーブル名
+var slugsTableName = os.Getenv("SLUGS_TABLE_NAME")         // スラッグのインデックス (主キーは slug)
+var revisionsTableName = os.Getenv("REVISIONS_TABLE_NAME") // リビジョン (履歴) のテーブル名
+
+var attachmentBaseURL = os.Getenv("ATTACHMENT_BASE_URL") // 添付ファイルの公開URLのベース (CDNなど、必須)
+
+var scheduler *publish.Scheduler
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+
+	scheduler = &publish.Scheduler{
+		Clock: clock.System,
+		Store: publish.DynamoScheduleStore{Publisher: &publish.Publisher{
+			Client:            dbClient,
+			DraftsTable:       draftsTableName,
+			PostsTable:        postsTableName,
+			SlugsTable:        slugsTableName,
+			RevisionsTable:    revisionsTableName,
+			AttachmentBaseURL: publish.AttachmentBaseURL(attachmentBaseURL),
+			Search:            search.StoreFromEnv(s3.NewFromConfig(cfg)),
+		}},
+	}
+}
+
+// Handler handles EventBridge scheduled events.
+func Handler(ctx context.Context, event events.CloudWatchEvent) (publish.Report, error) {
+	fmt.Printf("Received scheduled event %s for publish scheduled handler.\n", event.ID)
+
+	report, err := scheduler.Run(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	reportJSON, _ := json.Marshal(report)
+	fmt.Println("Scheduled publish report: " + string(reportJSON))
+
+	return report, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
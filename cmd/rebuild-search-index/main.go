@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+	"github.com/sunshine-724/my-homepage-backend/internal/publish"
+	"github.com/sunshine-724/my-homepage-backend/internal/search"
+)
+
+// 投稿テーブルの公開中の記事から全文検索のインデックスを作り直し、S3 のインデックスを置き換える
+// あわせて全記事の関連記事を計算し直す
+// 通常は公開・公開の取り消し・アーカイブ・復元のたびに差分で更新されるため、初回の構築と
+// 差分の更新に失敗した場合の復旧に使う (手動実行を想定している)
+
+// Report: 実行結果
+type Report struct {
+	Indexed int `json:"indexed"` // インデックスに登録した記事の数
+	Terms   int `json:"terms"`   // インデックスの語の数
+	Related int `json:"related"` // 関連記事を更新した記事の数
+}
+
+var dbClient *dynamodb.Client
+var postsTableName = os.Getenv("POSTS_TABLE_NAME") // 投稿テーブル名
+
+var searchStore *search.Store
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+	searchStore = search.StoreFromEnv(s3.NewFromConfig(cfg))
+}
+
+func Handler(ctx context.Context) (Report, error) {
+	fmt.Println("Received request for rebuild search index handler.")
+
+	items, err := posts.ScanAll(ctx, dbClient, postsTableName)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to scan posts: %w", err)
+	}
+
+	idx := search.New()
+	for _, item := range posts.Published(items) {
+		idx.Put(search.FromPost(item))
+	}
+	if err := searchStore.Replace(ctx, idx); err != nil {
+		return Report{}, err
+	}
+
+	// インデックスから全記事の関連記事を計算し直す
+	related, err := publish.RefreshRelated(ctx, dbClient, postsTableName, searchStore, time.Now())
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Indexed: len(idx.Docs), Terms: len(idx.Terms), Related: related}
+	fmt.Printf("Rebuilt search index: %d post(s), %d term(s), related posts of %d post(s) updated\n", report.Indexed, report.Terms, report.Related)
+	return report, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/attachments"
+)
+
+// バケット内の添付ファイルと下書き・投稿テーブルを突き合わせ、どちらにも存在しない {draftID}/ プレフィックス (孤児) を報告する
+// あわせて CLEANUP_TABLE_NAME に記録された削除失敗オブジェクトの削除を再試行する (スイーパー)
+// 手動実行 または EventBridge のスケジュールから起動することを想定している
+// 作成中の下書き (添付ファイルをアップロードした後、下書きを保存する前) を孤児と誤らないよう、
+// orphanGracePeriod 以内に更新されたオブジェクトを含むプレフィックスは孤児として扱わない
+
+// orphanGracePeriod: 孤児として扱うまでの猶予 (下書きの作成にかかる時間より十分長くする)
+const orphanGracePeriod = 24 * time.Hour
+
+// ReconcileRequest: 起動時のイベント
+type ReconcileRequest struct {
+	Delete bool `json:"delete"` // trueの場合は報告に加えて孤児を削除する
+}
+
+// Orphan: どのテーブルにも対応するアイテムが存在しない添付ファイルのプレフィックス
+type Orphan struct {
+	DraftID string   `json:"draftId"`
+	Keys    []string `json:"keys"`
+	Size    int64    `json:"size"` // 合計バイト数
+}
+
+// ReconcileReport: 実行結果
+type ReconcileReport struct {
+	PendingRetried int      `json:"pendingRetried"` // 再試行した削除失敗オブジェクトの数
+	PendingFailed  int      `json:"pendingFailed"`  // 再試行しても削除できなかった数
+	Orphans        []Orphan `json:"orphans"`
+	Deleted        bool     `json:"deleted"`
+}
+
+var dbClient *dynamodb.Client
+var draftsTableName = os.Getenv("DRAFTS_TABLE_NAME") // 下書きテーブル名
+var postsTableName = os.Getenv("POSTS_TABLE_NAME")   // 投稿テーブル名
+
+var s3Client *s3.Client
+var bucketName = os.Getenv("BUCKET_NAME")
+
+var cleanupTableName = os.Getenv("CLEANUP_TABLE_NAME") // 削除に失敗したS3オブジェクトを記録するテーブル名
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+	s3Client = s3.NewFromConfig(cfg)
+}
+
+func Handler(ctx context.Context, request ReconcileRequest) (ReconcileReport, error) {
+	fmt.Printf("Received request for reconcile attachments handler. delete=%v\n", request.Delete)
+
+	var report ReconcileReport
+
+	/* 1. 削除に失敗したオブジェクトの再試行 */
+	retried, failed, err := retryPendingDeletions(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to retry pending deletions: %w", err)
+	}
+	report.PendingRetried = retried
+	report.PendingFailed = failed
+
+	/* 2. 孤児プレフィックスの検出 */
+	orphans, err := findOrphans(ctx, time.Now())
+	if err != nil {
+		return report, fmt.Errorf("failed to find orphans: %w", err)
+	}
+	report.Orphans = orphans
+
+	/* 3. 孤児の削除 (delete=true の場合のみ) */
+	if request.Delete {
+		for _, orphan := range orphans {
+			fmt.Printf("Deleting orphaned attachments of draft %s (%d object(s))\n", orphan.DraftID, len(orphan.Keys))
+			failed := attachments.DeleteObjects(ctx, s3Client, bucketName, orphan.Keys)
+			attachments.RecordPendingDeletions(ctx, dbClient, cleanupTableName, bucketName, "reconcile orphan", failed)
+		}
+		report.Deleted = true
+	}
+
+	reportJSON, _ := json.Marshal(report)
+	fmt.Println("Reconcile report: " + string(reportJSON))
+
+	return report, nil
+}
+
+// retryPendingDeletions は記録済みの削除失敗オブジェクトを再度削除し、成功したものは記録から消す
+func retryPendingDeletions(ctx context.Context) (retried int, failed int, err error) {
+	if cleanupTableName == "" {
+		return 0, 0, nil
+	}
+
+	var pending []attachments.PendingDeletion
+	paginator := dynamodb.NewScanPaginator(dbClient, &dynamodb.ScanInput{
+		TableName: aws.String(cleanupTableName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		var items []attachments.PendingDeletion
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return 0, 0, err
+		}
+		pending = append(pending, items...)
+	}
+
+	for _, p := range pending {
+		retried++
+
+		stillFailed := attachments.DeleteObjects(ctx, s3Client, p.Bucket, []string{p.Key})
+		if len(stillFailed) > 0 {
+			failed++
+			attachments.RecordPendingDeletions(ctx, dbClient, cleanupTableName, p.Bucket, p.Reason, stillFailed)
+			continue
+		}
+
+		_, err := dbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(cleanupTableName),
+			Key: map[string]types.AttributeValue{
+				"key": &types.AttributeValueMemberS{Value: p.Key},
+			},
+		})
+		if err != nil {
+			fmt.Printf("Error removing pending deletion record %s: %v\n", p.Key, err)
+		}
+	}
+
+	return retried, failed, nil
+}
+
+// findOrphans はバケット全体を走査し、下書き・投稿のどちらにも存在しないプレフィックスを返す
+// now から orphanGracePeriod 以内に更新されたオブジェクトを含むプレフィックスは、作成中の下書きの可能性があるため返さない
+func findOrphans(ctx context.Context, now time.Time) ([]Orphan, error) {
+	byDraft := map[string]*Orphan{}
+	recent := map[string]bool{}
+
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			draftID, ok := attachments.DraftID(key)
+			if !ok {
+				continue
+			}
+			orphan, ok := byDraft[draftID]
+			if !ok {
+				orphan = &Orphan{DraftID: draftID}
+				byDraft[draftID] = orphan
+			}
+			orphan.Keys = append(orphan.Keys, key)
+			orphan.Size += aws.ToInt64(object.Size)
+			if object.LastModified != nil && now.Sub(*object.LastModified) < orphanGracePeriod {
+				recent[draftID] = true
+			}
+		}
+	}
+
+	var orphans []Orphan
+	for draftID, orphan := range byDraft {
+		if recent[draftID] {
+			fmt.Printf("Skipping attachments of draft %s updated within %s\n", draftID, orphanGracePeriod)
+			continue
+		}
+		referenced, err := isReferenced(ctx, draftID)
+		if err != nil {
+			return nil, err
+		}
+		if !referenced {
+			orphans = append(orphans, *orphan)
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].DraftID < orphans[j].DraftID })
+
+	return orphans, nil
+}
+
+// isReferenced は下書きテーブルか投稿テーブルにIDが存在するかを返す
+func isReferenced(ctx context.Context, draftID string) (bool, error) {
+	for _, tableName := range []string{draftsTableName, postsTableName} {
+		result, err := dbClient.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(tableName),
+			Key: map[string]types.AttributeValue{
+				"id": &types.AttributeValueMemberS{Value: draftID},
+			},
+			ProjectionExpression: aws.String("id"),
+		})
+		if err != nil {
+			return false, err
+		}
+		if result.Item != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+	"github.com/sunshine-724/my-homepage-backend/internal/publish"
+	"github.com/sunshine-724/my-homepage-backend/internal/revision"
+	"github.com/sunshine-724/my-homepage-backend/internal/search"
+	"github.com/sunshine-724/my-homepage-backend/internal/textstat"
+	"github.com/sunshine-724/my-homepage-backend/internal/timestamp"
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
+)
+
+// POST /posts/{id}/revisions/{rev}/restore 公開済みの記事を以前のリビジョンの内容に戻す
+// 履歴は書き換えず、復元した内容を新しいリビジョンとして記録してから再公開する
+// If-Match には記事のversionを指定する
+
+var dbClient *dynamodb.Client
+var postsTableName = os.Getenv("POSTS_TABLE_NAME")         // 投稿テーブル名
+var revisionsTableName = os.Getenv("REVISIONS_TABLE_NAME") // リビジョン (履歴) のテーブル名
+
+var attachmentBaseURL = os.Getenv("ATTACHMENT_BASE_URL") // 添付ファイルの公開URLのベース (CDNなど、必須)
+
+var searchStore *search.Store // 全文検索のインデックス
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+
+	attachmentBaseURL = publish.AttachmentBaseURL(attachmentBaseURL)
+	searchStore = search.StoreFromEnv(s3.NewFromConfig(cfg))
+}
+
+// Handler handles the API Gateway proxy request to restore a post to an earlier revision.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Println("Received request for restore revision handler.")
+
+	id := request.PathParameters["id"]
+	if id == "" {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Missing post ID"}, nil
+	}
+	rev, err := strconv.Atoi(request.PathParameters["rev"])
+	if err != nil || rev < 1 {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Invalid revision: %s", request.PathParameters["rev"])}, nil
+	}
+
+	expected, err := version.IfMatch(request)
+	if response, ok := version.ErrorResponse(err); ok {
+		return response, nil
+	}
+
+	/* 1. 記事と復元するリビジョンを取得 */
+	post, err := posts.Get(ctx, dbClient, postsTableName, id)
+	if err != nil {
+		fmt.Printf("Error getting post %s: %v\n", id, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get post: %v", err)}, nil
+	}
+	if post == nil {
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Post with ID %s not found", id)}, nil
+	}
+	if response, ok := version.ErrorResponse(version.Check(expected, post.Version)); ok {
+		return response, nil
+	}
+
+	source, err := revision.Get(ctx, dbClient, revisionsTableName, id, rev)
+	if err != nil {
+		fmt.Printf("Error getting revision %s@%d: %v\n", id, rev, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get revision: %v", err)}, nil
+	}
+	if source == nil {
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Revision %d of %s not found", rev, id)}, nil
+	}
+
+	/* 2. 復元する本文を現在の添付ファイルで再レンダリングし、目次・文字数・読了時間・要約を作り直す */
+	rendered, err := publish.Render(source.Content, post.AttachmentFilePath, attachmentBaseURL)
+	if err != nil {
+		fmt.Printf("Error rendering content: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to render content: %v", err)}, nil
+	}
+	for _, warning := range rendered.Warnings {
+		fmt.Printf("Render warning for %s: %s\n", id, warning)
+	}
+	stats := textstat.Analyze(source.Content)
+	// 公開時と同じく、空の日付・RFC 3339 の日時を表示用の日付に整える
+	now := time.Now()
+	date := timestamp.Date(source.Date, now)
+
+	/* 3. 記事の更新と新しいリビジョンの記録を1つのトランザクションで行う */
+	next, err := revision.Next(ctx, dbClient, revisionsTableName, id)
+	if err != nil {
+		fmt.Printf("Error getting next revision: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get revisions: %v", err)}, nil
+	}
+	restored := revision.New(id, next, source.Title, date, source.Content, source.Tags, revision.AuthorFromRequest(request), revision.SourceRestore)
+	restored.RestoredFrom = rev
+	revisionPut, err := revision.Put(revisionsTableName, restored)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to marshal revision: %v", err)}, nil
+	}
+
+	tags, err := attributevalue.Marshal(source.Tags)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to marshal tags: %v", err)}, nil
+	}
+	toc, err := attributevalue.Marshal(rendered.TOC)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to marshal toc: %v", err)}, nil
+	}
+
+	// 読み出してから記事が更新されていた場合は上書きしない
+	condition, names, values := version.Condition(post.Version)
+	update := "SET title = :title, #date = :date, content = :content, contentHtml = :contentHtml, tags = :tags, toc = :toc, charCount = :charCount, wordCount = :wordCount, readingTime = :readingTime, excerpt = :excerpt, updatedAt = :updatedAt, #version = :nextVersion"
+	// 公開中の記事は日付のインデックスのソートキーも復元した日付に合わせる
+	if listingDate := posts.ListingDate(date, post.PublishedAt); post.IsPublished && !post.Archived && listingDate != "" {
+		update += ", listingDate = :listingDate"
+		values[":listingDate"] = &types.AttributeValueMemberS{Value: listingDate}
+	}
+	names["#date"] = "date"
+	maps.Copy(values, map[string]types.AttributeValue{
+		":title":       &types.AttributeValueMemberS{Value: source.Title},
+		":date":        &types.AttributeValueMemberS{Value: date},
+		":content":     &types.AttributeValueMemberS{Value: source.Content},
+		":contentHtml": &types.AttributeValueMemberS{Value: rendered.HTML},
+		":tags":        tags,
+		":toc":         toc,
+		":charCount":   &types.AttributeValueMemberN{Value: strconv.Itoa(stats.CharCount)},
+		":wordCount":   &types.AttributeValueMemberN{Value: strconv.Itoa(stats.WordCount)},
+		":readingTime": &types.AttributeValueMemberN{Value: strconv.Itoa(stats.ReadingTime)},
+		":excerpt":     &types.AttributeValueMemberS{Value: stats.Excerpt},
+		":nextVersion": &types.AttributeValueMemberN{Value: strconv.Itoa(post.Version + 1)},
+		":updatedAt":   &types.AttributeValueMemberS{Value: timestamp.Format(now)},
+	})
+
+	_, err = dbClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Update: &types.Update{
+				TableName: aws.String(postsTableName),
+				Key: map[string]types.AttributeValue{
+					"id": &types.AttributeValueMemberS{Value: id},
+				},
+				UpdateExpression:          aws.String(update),
+				ConditionExpression:       aws.String("attribute_exists(id) AND " + condition),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			}},
+			revisionPut,
+		},
+	})
+	if version.ConditionFailed(err, 0) {
+		mismatch, err := version.Conflict(ctx, dbClient, postsTableName, id)
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get post: %v", err)}, nil
+		}
+		if mismatch == nil {
+			return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Post with ID %s not found", id)}, nil
+		}
+		response, _ := version.ErrorResponse(mismatch)
+		return response, nil
+	}
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		fmt.Printf("Restore of %s was canceled: %v\n", id, err)
+		return events.APIGatewayProxyResponse{StatusCode: 409, Body: fmt.Sprintf("Post with ID %s was modified concurrently", id)}, nil
+	}
+	if err != nil {
+		fmt.Printf("Error restoring post: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to update post: %v", err)}, nil
+	}
+	fmt.Printf("Restored %s to revision %d as revision %d by %s\n", id, rev, next, restored.Author)
+
+	/* 4. 検索インデックスを復元した内容で更新 (失敗しても rebuild-search-index で作り直せる) */
+	if post.IsPublished && !post.Archived {
+		restoredPost := *post
+		restoredPost.Title, restoredPost.Date, restoredPost.Content, restoredPost.Tags = source.Title, date, source.Content, source.Tags
+		err := searchStore.Update(ctx, func(idx *search.Index) { idx.Put(search.FromPost(restoredPost)) })
+		if err != nil {
+			fmt.Printf("Error updating search index for %s: %v\n", id, err)
+		}
+	}
+
+	/* 5. 復元した記事と、その記事に関わる記事の関連記事を計算し直す */
+	nextVersion := post.Version + 1
+	if post.IsPublished && !post.Archived {
+		if err := publish.UpdateRelated(ctx, dbClient, postsTableName, searchStore, now, id); err != nil {
+			fmt.Printf("Error updating related posts: %v\n", err)
+		}
+	}
+
+	response := map[string]any{
+		"message":      fmt.Sprintf("Post %s restored to revision %d", id, rev),
+		"id":           id,
+		"revision":     next,
+		"restoredFrom": rev,
+		"author":       restored.Author,
+		"version":      nextVersion,
+	}
+	if len(rendered.Warnings) > 0 {
+		response["warnings"] = rendered.Warnings
+	}
+	responseBody, _ := json.Marshal(response)
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    map[string]string{"Content-Type": "application/json", "ETag": version.ETag(nextVersion)},
+		Body:       string(responseBody),
+	}, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
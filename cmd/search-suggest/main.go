@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/httpcache"
+	"github.com/sunshine-724/my-homepage-backend/internal/search"
+)
+
+// GET /search/suggest?q={入力中の文字列}&limit={件数}
+// 検索語の入力補完として、記事のタイトル・タグ・頻出語のうち q で始まるものを返す
+// カタカナとひらがなは区別しない (「でー」でも「データベース」に一致する)
+// 候補は検索インデックスを更新するたびに作り直される S3 の小さなオブジェクトから読み出す
+
+const (
+	defaultLimit    = 8  // 候補の件数の既定値
+	maxLimit        = 20 // 候補の件数の上限
+	maxPrefixLength = 50 // q の最大文字数
+)
+
+// defaultCacheControl: CACHE_CONTROL でルートのポリシーが設定されていない場合の Cache-Control
+// 入力のたびに呼ばれるため、CDN やブラウザのキャッシュを使えるようにする
+const defaultCacheControl = "public, max-age=300"
+
+// Suggestion: 入力補完の候補
+type Suggestion struct {
+	Text string `json:"text"`
+	Type string `json:"type"`           // title / tag / term
+	ID   string `json:"id,omitempty"`   // title の場合は記事のID
+	Slug string `json:"slug,omitempty"` // title の場合は記事のスラッグ
+}
+
+// Response: 入力補完の結果
+type Response struct {
+	Query       string       `json:"query"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+var searchStore *search.Store
+var cachePolicies = httpcache.PoliciesFromEnv()
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	searchStore = search.StoreFromEnv(s3.NewFromConfig(cfg))
+}
+
+// Handler handles the API Gateway proxy request to suggest search terms.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Println("Received request for search suggest handler.")
+
+	query := strings.TrimSpace(request.QueryStringParameters["q"])
+	if query == "" {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Missing query parameter q"}, nil
+	}
+	if utf8.RuneCountInString(query) > maxPrefixLength {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Query must be at most %d characters", maxPrefixLength)}, nil
+	}
+	limit := defaultLimit
+	if value := request.QueryStringParameters["limit"]; value != "" {
+		v, err := strconv.Atoi(value)
+		if err != nil || v < 1 || v > maxLimit {
+			return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Invalid limit: %s (1-%d)", value, maxLimit)}, nil
+		}
+		limit = v
+	}
+
+	suggestions, err := searchStore.LoadSuggestions(ctx)
+	if err != nil {
+		fmt.Printf("Error loading search suggestions: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to load search suggestions: %v", err)}, nil
+	}
+
+	response := Response{Query: query, Suggestions: []Suggestion{}}
+	for _, m := range suggestions.Match(query, limit, time.Now()) {
+		response.Suggestions = append(response.Suggestions, Suggestion{Text: m.Text, Type: m.Type, ID: m.ID, Slug: m.Slug})
+	}
+
+	responseBody, err := json.Marshal(response)
+	if err != nil {
+		fmt.Printf("Error marshalling response body: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "Failed to marshal response"}, nil
+	}
+
+	validators := httpcache.Validators{ETag: httpcache.ETag(responseBody)}
+	cacheControl := cachePolicies.For(httpcache.Route(request), defaultCacheControl)
+	return httpcache.Respond(request, map[string]string{"Content-Type": "application/json"}, string(responseBody), validators, cacheControl), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/httpcache"
+	"github.com/sunshine-724/my-homepage-backend/internal/search"
+)
+
+// GET /search?q={検索語}&page={ページ}&limit={件数}
+// 公開済みの記事をタイトル・タグ・本文から検索し、スコアの高い順に一致した箇所のスニペットと一緒に返す
+// インデックスは publish-post などが更新したものを S3 から読み出す (変更が無ければ実行環境でキャッシュしたものを使う)
+
+const (
+	defaultLimit   = 10  // 1ページの件数の既定値
+	maxLimit       = 50  // 1ページの件数の上限
+	maxQueryLength = 200 // 検索語の最大文字数
+)
+
+// defaultCacheControl: CACHE_CONTROL でルートのポリシーが設定されていない場合の Cache-Control
+const defaultCacheControl = "public, max-age=60"
+
+// Response: 検索結果
+type Response struct {
+	Query   string       `json:"query"`
+	Total   int          `json:"total"` // 一致した記事の総数
+	Page    int          `json:"page"`
+	Limit   int          `json:"limit"`
+	Results []search.Hit `json:"results"`
+}
+
+var searchStore *search.Store
+var cachePolicies = httpcache.PoliciesFromEnv()
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	searchStore = search.StoreFromEnv(s3.NewFromConfig(cfg))
+}
+
+// Handler handles the API Gateway proxy request to search published posts.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Println("Received request for search handler.")
+
+	query := strings.TrimSpace(request.QueryStringParameters["q"])
+	if query == "" {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Missing query parameter q"}, nil
+	}
+	if utf8.RuneCountInString(query) > maxQueryLength {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Query must be at most %d characters", maxQueryLength)}, nil
+	}
+	limit, err := intParameter(request, "limit", defaultLimit)
+	if err != nil || limit < 1 || limit > maxLimit {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Invalid limit: %s (1-%d)", request.QueryStringParameters["limit"], maxLimit)}, nil
+	}
+	// (page - 1) * limit が溢れないページだけを受け付ける
+	page, err := intParameter(request, "page", 1)
+	if err != nil || page < 1 || page > math.MaxInt/limit {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Invalid page: %s", request.QueryStringParameters["page"])}, nil
+	}
+
+	idx, err := searchStore.Load(ctx)
+	if err != nil {
+		fmt.Printf("Error loading search index: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to load search index: %v", err)}, nil
+	}
+
+	hits := idx.Search(query, time.Now())
+	response := Response{Query: query, Total: len(hits), Page: page, Limit: limit, Results: []search.Hit{}}
+	if start := (page - 1) * limit; start < len(hits) {
+		response.Results = hits[start:min(len(hits), start+limit)]
+	}
+	fmt.Printf("Search %q matched %d post(s)\n", query, len(hits))
+
+	responseBody, err := json.Marshal(response)
+	if err != nil {
+		fmt.Printf("Error marshalling response body: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: "Failed to marshal response"}, nil
+	}
+
+	validators := httpcache.Validators{ETag: httpcache.ETag(responseBody)}
+	cacheControl := cachePolicies.For(httpcache.Route(request), defaultCacheControl)
+	return httpcache.Respond(request, map[string]string{"Content-Type": "application/json"}, string(responseBody), validators, cacheControl), nil
+}
+
+// intParameter は整数のクエリパラメータを読む (省略された場合は fallback)
+func intParameter(request events.APIGatewayProxyRequest, name string, fallback int) (int, error) {
+	value := request.QueryStringParameters[name]
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func main() {
+	lambda.Start(Handler)
+}
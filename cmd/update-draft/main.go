@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/drafts"
+	"github.com/sunshine-724/my-homepage-backend/internal/publish"
+	"github.com/sunshine-724/my-homepage-backend/internal/revision"
+	"github.com/sunshine-724/my-homepage-backend/internal/timestamp"
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
+)
+
+// PUT /drafts/{id} 下書きを更新し、更新ごとにリビジョンを記録する
+// If-Match ヘッダーに GET /drafts/{id} の ETag (version) を指定し、他のタブなどで先に更新されていた場合は412を返す
+// 添付ファイルは変更しない。TTLは更新のたびに現在時刻から保持期間 (DRAFT_TTL) 後まで延ばす (固定された下書きを除く)
+
+// RequestBody: 更新するフィールド (省略したフィールドは変更しない)
+type RequestBody struct {
+	Title     *string   `json:"title"`
+	Date      *string   `json:"date"`
+	Content   *string   `json:"content"`
+	Tags      *[]string `json:"tags"`
+	NoIndex   *bool     `json:"noIndex"`
+	PublishAt *string   `json:"publishAt"` // 予約公開の日時 (RFC 3339)。空文字列で予約を取り消す
+	ExpireAt  *string   `json:"expireAt"`  // 公開終了の日時 (RFC 3339)。空文字列で取り消す
+}
+
+// DraftItem: DynamoDBの下書きテーブルに保存されているデータ構造
+type DraftItem struct {
+	ID                 string   `dynamodbav:"id"`
+	Title              string   `dynamodbav:"title"`
+	Date               string   `dynamodbav:"date"`
+	Content            string   `dynamodbav:"content"`
+	Tags               []string `dynamodbav:"tags"`
+	AttachmentFilePath []string `dynamodbav:"attachmentFilePath"` // S3に保存したファイルのパス
+	IsPublished        bool     `dynamodbav:"isPublished"`
+	NoIndex            bool     `dynamodbav:"noindex,omitempty"`
+	PublishAt          string   `dynamodbav:"publishAt,omitempty"` // 予約公開の日時 (UTCのRFC 3339)
+	ExpireAt           string   `dynamodbav:"expireAt,omitempty"`  // 公開終了の日時 (UTCのRFC 3339、公開後の記事に引き継ぐ)
+	Pinned             bool     `dynamodbav:"pinned,omitempty"`    // POST /drafts/{id}/keep で固定された (TTLが無い)
+	TTL                int64    `dynamodbav:"ttl,omitempty"`
+	Version            int      `dynamodbav:"version"`             // 楽観的排他制御のためのバージョン (更新のたびに1増やす)
+	CreatedAt          string   `dynamodbav:"createdAt,omitempty"` // 作成した日時 (UTCのRFC 3339)
+	UpdatedAt          string   `dynamodbav:"updatedAt"`           // 最後に更新した日時 (UTCのRFC 3339)
+}
+
+var dbClient *dynamodb.Client
+var draftsTableName = os.Getenv("DRAFTS_TABLE_NAME")       // 下書きテーブル名
+var revisionsTableName = os.Getenv("REVISIONS_TABLE_NAME") // リビジョン (履歴) のテーブル名
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+	}
+	dbClient = dynamodb.NewFromConfig(cfg)
+}
+
+// Handler handles the API Gateway proxy request to update a draft.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	fmt.Println("Received request for update draft handler.")
+
+	id := request.PathParameters["id"]
+	if id == "" {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: "Missing draft ID"}, nil
+	}
+
+	expected, err := version.IfMatch(request)
+	if response, ok := version.ErrorResponse(err); ok {
+		return response, nil
+	}
+
+	var reqBody RequestBody
+	if err := json.Unmarshal([]byte(request.Body), &reqBody); err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: fmt.Sprintf("Failed to unmarshal request body: %v", err)}, nil
+	}
+
+	/* 1. 現在の下書きを取得 */
+	result, err := dbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(draftsTableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		fmt.Printf("Error getting item from DynamoDB: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get draft: %v", err)}, nil
+	}
+	if result.Item == nil {
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Draft with ID %s not found", id)}, nil
+	}
+
+	var item DraftItem
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to unmarshal draft: %v", err)}, nil
+	}
+	if response, ok := version.ErrorResponse(version.Check(expected, item.Version)); ok {
+		return response, nil
+	}
+
+	/* 2. 指定されたフィールドだけを上書き */
+	now := time.Now()
+	if reqBody.Title != nil {
+		item.Title = *reqBody.Title
+	}
+	if reqBody.Date != nil {
+		// 空文字列の場合は今日の日付 (SITE_TIMEZONE) にする
+		item.Date = timestamp.Date(*reqBody.Date, now)
+	}
+	if reqBody.Content != nil {
+		item.Content = *reqBody.Content
+	}
+	if reqBody.Tags != nil {
+		item.Tags = *reqBody.Tags
+	}
+	if reqBody.NoIndex != nil {
+		item.NoIndex = *reqBody.NoIndex
+	}
+	if reqBody.PublishAt != nil {
+		publishAt, err := publish.ParsePublishAt(*reqBody.PublishAt)
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: 400, Body: err.Error()}, nil
+		}
+		item.PublishAt = publishAt
+	}
+	if reqBody.ExpireAt != nil {
+		expireAt, err := publish.ParseExpireAt(*reqBody.ExpireAt)
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: 400, Body: err.Error()}, nil
+		}
+		item.ExpireAt = expireAt
+	}
+	if err := publish.ValidateSchedule(item.PublishAt, item.ExpireAt); err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: err.Error()}, nil
+	}
+
+	// 編集中の下書きが期限切れで消えないよう、更新のたびにTTLを延ばす
+	// 予約公開する下書きは公開日時より前に期限切れにならないようにする
+	if !item.Pinned {
+		item.TTL = publish.ScheduledTTL(max(item.TTL, drafts.TTL(now)), item.PublishAt)
+	}
+	item.UpdatedAt = timestamp.Format(now)
+
+	/* 3. 下書きの保存とリビジョンの記録を1つのトランザクションで行う */
+	rev, err := revision.Next(ctx, dbClient, revisionsTableName, id)
+	if err != nil {
+		fmt.Printf("Error getting next revision: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get revisions: %v", err)}, nil
+	}
+	revisionPut, err := revision.Put(revisionsTableName, revision.New(
+		id, rev, item.Title, item.Date, item.Content, item.Tags, revision.AuthorFromRequest(request), revision.SourceDraft,
+	))
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to marshal revision: %v", err)}, nil
+	}
+
+	// 取得した時点のversionから変わっていない場合だけ書き込む (取得後に公開・削除された場合も書き戻さない)
+	condition, names, values := version.Condition(item.Version)
+	item.Version++
+
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to marshal item: %v", err)}, nil
+	}
+
+	_, err = dbClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				Item:                      av,
+				TableName:                 aws.String(draftsTableName),
+				ConditionExpression:       aws.String("attribute_exists(id) AND " + condition),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			}},
+			revisionPut,
+		},
+	})
+	if version.ConditionFailed(err, 0) {
+		fmt.Printf("Update of draft %s was rejected: %v\n", id, err)
+		mismatch, err := version.Conflict(ctx, dbClient, draftsTableName, id)
+		if err != nil {
+			return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to get draft: %v", err)}, nil
+		}
+		if mismatch == nil {
+			return events.APIGatewayProxyResponse{StatusCode: 404, Body: fmt.Sprintf("Draft with ID %s not found", id)}, nil
+		}
+		response, _ := version.ErrorResponse(mismatch)
+		return response, nil
+	}
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		// 同じ番号のリビジョンが同時に記録された
+		fmt.Printf("Update of draft %s was canceled: %v\n", id, err)
+		return events.APIGatewayProxyResponse{StatusCode: 409, Body: fmt.Sprintf("Draft with ID %s was modified concurrently", id)}, nil
+	}
+	if err != nil {
+		fmt.Printf("Error updating draft: %v\n", err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to put item to DynamoDB: %v", err)}, nil
+	}
+	fmt.Printf("Updated draft %s (revision %d)\n", id, rev)
+
+	response := map[string]any{"id": id, "revision": rev, "version": item.Version, "pinned": item.Pinned, "updatedAt": item.UpdatedAt}
+	if item.TTL > 0 {
+		response["ttl"] = item.TTL
+		response["expiresAt"] = time.Unix(item.TTL, 0).UTC().Format(time.RFC3339)
+	}
+	responseBody, _ := json.Marshal(response)
+	return events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers:    map[string]string{"Content-Type": "application/json", "ETag": version.ETag(item.Version)},
+		Body:       string(responseBody),
+	}, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,190 @@
+// Package attachments は下書きに紐づくS3添付ファイルの一覧・公開URLの組み立て・削除と、削除に失敗したオブジェクトの記録を扱う
+package attachments
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// deleteBatchSize: DeleteObjectsで一度に削除できるキーの上限
+const deleteBatchSize = 1000
+
+// PendingDeletion: 削除に失敗し、スイーパーによる再試行を待っているS3オブジェクト
+type PendingDeletion struct {
+	Key      string `dynamodbav:"key"` // S3のオブジェクトキー (テーブルの主キー)
+	Bucket   string `dynamodbav:"bucket"`
+	Reason   string `dynamodbav:"reason"`   // 削除が必要になった理由 (ex. "create-draft rollback")
+	Error    string `dynamodbav:"error"`    // 最後に発生したエラー
+	FailedAt string `dynamodbav:"failedAt"` // RFC 3339
+}
+
+// Prefix は下書きIDに対応する添付ファイルのプレフィックス ({draftID}/) を返す
+func Prefix(draftID string) string {
+	return draftID + "/"
+}
+
+// DraftID はオブジェクトキーから添付ファイルの下書きIDを取り出す (Prefix の逆)
+// {draftID}/ の形式でないキーの場合は ok が false になる
+func DraftID(key string) (draftID string, ok bool) {
+	draftID, _, found := strings.Cut(key, "/")
+	if !found || draftID == "" {
+		return "", false
+	}
+	return draftID, true
+}
+
+// ListKeys はprefix配下のオブジェクトキーを全て返す
+func ListKeys(ctx context.Context, client *s3.Client, bucket, prefix string) ([]string, error) {
+	var keys []string
+
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+
+	return keys, nil
+}
+
+// DeleteObjects は指定されたキーをまとめて削除し、削除できなかったキーを返す
+func DeleteObjects(ctx context.Context, client *s3.Client, bucket string, keys []string) (failed map[string]string) {
+	failed = map[string]string{}
+
+	for start := 0; start < len(keys); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(keys))
+		batch := keys[start:end]
+
+		objects := make([]types.ObjectIdentifier, 0, len(batch))
+		for _, key := range batch {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		output, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			// バッチ全体が失敗した場合は全てのキーを失敗扱いにする
+			for _, key := range batch {
+				failed[key] = err.Error()
+			}
+			continue
+		}
+
+		for _, e := range output.Errors {
+			failed[aws.ToString(e.Key)] = fmt.Sprintf("%s: %s", aws.ToString(e.Code), aws.ToString(e.Message))
+		}
+	}
+
+	return failed
+}
+
+// RecordPendingDeletions は削除に失敗したキーをテーブルに記録し、後からスイーパーが再試行できるようにする
+// tableNameが空の場合はログ出力のみ行う
+func RecordPendingDeletions(ctx context.Context, client *dynamodb.Client, tableName, bucket, reason string, failed map[string]string) {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	for key, cause := range failed {
+		fmt.Printf("Failed to delete S3 object %s: %s\n", key, cause)
+		if tableName == "" {
+			continue
+		}
+
+		av, err := attributevalue.MarshalMap(PendingDeletion{
+			Key:      key,
+			Bucket:   bucket,
+			Reason:   reason,
+			Error:    cause,
+			FailedAt: now,
+		})
+		if err != nil {
+			fmt.Printf("Error marshalling pending deletion for %s: %v\n", key, err)
+			continue
+		}
+
+		_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(tableName),
+			Item:      av,
+		})
+		if err != nil {
+			fmt.Printf("Error recording pending deletion for %s: %v\n", key, err)
+		}
+	}
+}
+
+// File: 添付ファイルとその縮小版
+type File struct {
+	Key      string // S3のオブジェクトキー ({draftID}/{fileName})
+	Name     string // ファイル名
+	Variants []Variant
+}
+
+// Variant: レスポンシブ画像用の縮小版
+// 元ファイルと同じプレフィックスに {stem}@{width}w{ext} (ex. example@640w.png) という名前で置かれる
+type Variant struct {
+	Key   string
+	Width int
+}
+
+var variantPattern = regexp.MustCompile(`^(.+)@([0-9]+)w(\.[^.]*)?$`)
+
+// Group はオブジェクトキーの一覧を元ファイルごとにまとめる
+// 元ファイルが存在しない縮小版は通常のファイルとして扱う
+func Group(keys []string) []File {
+	var files []File
+	index := map[string]int{} // 元ファイルのキー -> filesの位置
+	var variants []string
+
+	for _, key := range keys {
+		if variantPattern.MatchString(path.Base(key)) {
+			variants = append(variants, key)
+			continue
+		}
+		index[key] = len(files)
+		files = append(files, File{Key: key, Name: path.Base(key)})
+	}
+
+	for _, key := range variants {
+		m := variantPattern.FindStringSubmatch(path.Base(key))
+		width, _ := strconv.Atoi(m[2])
+		original := path.Join(path.Dir(key), m[1]+m[3])
+
+		i, ok := index[original]
+		if !ok {
+			files = append(files, File{Key: key, Name: path.Base(key)})
+			continue
+		}
+		files[i].Variants = append(files[i].Variants, Variant{Key: key, Width: width})
+	}
+
+	return files
+}
+
+// PublicURL は公開用のベースURL (CDNなど) とオブジェクトキーからURLを組み立てる
+func PublicURL(baseURL, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.Join(segments, "/")
+}
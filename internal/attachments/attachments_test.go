@@ -0,0 +1,25 @@
+package attachments
+
+import "testing"
+
+func TestDraftID(t *testing.T) {
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"draft-1/image.png", "draft-1", true},
+		{"draft-1/sub/image@640w.png", "draft-1", true},
+		{"_draft/image.png", "_draft", true},
+		{"no-prefix.png", "", false},
+		{"/image.png", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := DraftID(tt.key)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("DraftID(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
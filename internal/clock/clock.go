@@ -0,0 +1,22 @@
+// Package clock は現在時刻の取得を抽象化する
+// 予約公開のように時刻に依存する処理へ固定の時刻を渡して動作を確認できるようにする
+package clock
+
+import "time"
+
+// Clock は現在時刻を返す
+type Clock interface {
+	Now() time.Time
+}
+
+// System: 実際の時刻を返すClock
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Fixed は常に同じ時刻を返すClock
+type Fixed time.Time
+
+func (f Fixed) Now() time.Time { return time.Time(f) }
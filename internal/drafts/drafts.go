@@ -0,0 +1,152 @@
+// Package drafts は下書きテーブル (blog_drafts) の保持期間 (TTL) を扱う
+package drafts
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/timestamp"
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
+)
+
+// DefaultLifetime: DRAFT_TTL が未設定の場合の下書きの保持期間
+const DefaultLifetime = 7 * 24 * time.Hour
+
+// Lifetime は下書きの保持期間を返す
+// DRAFT_TTL に Go の time.Duration の形式 (ex. "336h") または日数 (ex. "14d") で指定する
+func Lifetime() time.Duration {
+	value := os.Getenv("DRAFT_TTL")
+	if value == "" {
+		return DefaultLifetime
+	}
+	d, err := ParseLifetime(value)
+	if err != nil {
+		fmt.Printf("Ignoring DRAFT_TTL: %v\n", err)
+		return DefaultLifetime
+	}
+	return d
+}
+
+// ParseLifetime は保持期間の文字列を解釈する
+func ParseLifetime(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid lifetime %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid lifetime %q", s)
+		}
+		d = parsed
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("lifetime must be positive: %q", s)
+	}
+	return d, nil
+}
+
+// TTL はnowから保持期間が経過した時刻 (DynamoDBのTTLに使うUNIX時間) を返す
+func TTL(now time.Time) int64 {
+	return now.Add(Lifetime()).Unix()
+}
+
+// Keep は下書きを固定し、期限切れで削除されないようにする (TTL属性を削除する)
+// 読み出した時点の version (current) から変わっていない場合だけ更新し、version を1増やす
+// 条件を満たさない場合は ConditionalCheckFailedException を返す
+func Keep(ctx context.Context, client *dynamodb.Client, tableName, id string, current int) error {
+	return update(ctx, client, tableName, id, current, "SET pinned = :true, #version = :nextVersion, updatedAt = :updatedAt REMOVE #ttl", map[string]types.AttributeValue{
+		":true": &types.AttributeValueMemberBOOL{Value: true},
+	})
+}
+
+// Unkeep は下書きの固定を解除し、TTLを設定し直す (条件と version の扱いは Keep と同じ)
+func Unkeep(ctx context.Context, client *dynamodb.Client, tableName, id string, current int, ttl int64) error {
+	return update(ctx, client, tableName, id, current, "SET #ttl = :ttl, #version = :nextVersion, updatedAt = :updatedAt REMOVE pinned", map[string]types.AttributeValue{
+		":ttl": &types.AttributeValueMemberN{Value: strconv.FormatInt(ttl, 10)},
+	})
+}
+
+func update(ctx context.Context, client *dynamodb.Client, tableName, id string, current int, expression string, values map[string]types.AttributeValue) error {
+	condition, names, conditionValues := version.Condition(current)
+	names["#ttl"] = "ttl"
+	maps.Copy(values, conditionValues)
+	values[":nextVersion"] = &types.AttributeValueMemberN{Value: strconv.Itoa(current + 1)}
+	values[":updatedAt"] = &types.AttributeValueMemberS{Value: timestamp.Format(time.Now())}
+
+	_, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       aws.String("attribute_exists(id) AND " + condition),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	return err
+}
+
+// Expiring: まもなく期限切れになる下書き
+type Expiring struct {
+	ID        string `json:"id" dynamodbav:"id"`
+	Title     string `json:"title" dynamodbav:"title"`
+	TTL       int64  `json:"ttl" dynamodbav:"ttl"`
+	ExpiresAt string `json:"expiresAt" dynamodbav:"-"` // TTLをRFC 3339に変換したもの
+}
+
+// ExpiringWithin はnowからwindowの間に期限切れになる下書きを、期限の早い順に返す
+// 既に期限切れでまだ削除されていない下書きと、固定された (TTLの無い) 下書きは含めない
+func ExpiringWithin(ctx context.Context, client *dynamodb.Client, tableName string, now time.Time, window time.Duration) ([]Expiring, error) {
+	var expiring []Expiring
+
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:            aws.String(tableName),
+		FilterExpression:     aws.String("#ttl > :now AND #ttl <= :until"),
+		ProjectionExpression: aws.String("id, title, #ttl"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":   &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			":until": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(window).Unix(), 10)},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var items []Expiring
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, err
+		}
+		expiring = append(expiring, items...)
+	}
+
+	for i := range expiring {
+		expiring[i].ExpiresAt = time.Unix(expiring[i].TTL, 0).UTC().Format(time.RFC3339)
+	}
+	sort.Slice(expiring, func(i, j int) bool {
+		if expiring[i].TTL != expiring[j].TTL {
+			return expiring[i].TTL < expiring[j].TTL
+		}
+		return expiring[i].ID < expiring[j].ID
+	})
+
+	return expiring, nil
+}
@@ -0,0 +1,174 @@
+// Package feed は公開済みの記事から RSS 2.0 / Atom / JSON Feed 1.1 を生成する
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"html"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Feed: フィードの共通表現 (各フォーマットへはここから変換する)
+type Feed struct {
+	Title       string
+	Description string
+	Link        string // サイトのURL
+	FeedURL     string // このフィード自身のURL
+	Language    string
+	Updated     time.Time
+	Items       []Item
+}
+
+// Item: フィードの1エントリー
+type Item struct {
+	ID          string
+	Title       string
+	Link        string
+	Published   time.Time
+	Updated     time.Time
+	Categories  []string
+	Summary     string // プレーンテキストの要約
+	ContentHTML string // 全文のHTML (空の場合は要約のみ配信する)
+}
+
+// RSS は RSS 2.0 形式で出力する
+func (f Feed) RSS() ([]byte, error) {
+	channel := rssChannel{
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Description,
+		Language:      f.Language,
+		LastBuildDate: rfc822(f.Updated),
+		AtomLink:      rssAtomLink{Href: f.FeedURL, Rel: "self", Type: "application/rss+xml"},
+	}
+	for _, item := range f.Items {
+		entry := rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{Value: item.Link, IsPermaLink: "true"},
+			PubDate:     rfc822(item.Published),
+			Categories:  item.Categories,
+			Description: item.Summary,
+		}
+		if item.ContentHTML != "" {
+			entry.Content = &rssContent{Value: item.ContentHTML}
+		}
+		channel.Items = append(channel.Items, entry)
+	}
+
+	return marshalXML(rss{
+		Version:      "2.0",
+		XMLNSAtom:    "http://www.w3.org/2005/Atom",
+		XMLNSContent: "http://purl.org/rss/1.0/modules/content/",
+		Channel:      channel,
+	})
+}
+
+// Atom は Atom (RFC 4287) 形式で出力する
+func (f Feed) Atom() ([]byte, error) {
+	feed := atomFeed{
+		XMLNS:   "http://www.w3.org/2005/Atom",
+		Lang:    f.Language,
+		Title:   f.Title,
+		ID:      f.FeedURL,
+		Updated: rfc3339(f.Updated),
+		Links: []atomLink{
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+			{Href: f.FeedURL, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+	if f.Description != "" {
+		feed.Subtitle = f.Description
+	}
+	for _, item := range f.Items {
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        item.Link,
+			Links:     []atomLink{{Href: item.Link, Rel: "alternate", Type: "text/html"}},
+			Published: rfc3339(item.Published),
+			Updated:   rfc3339(item.Updated),
+			Summary:   &atomText{Type: "text", Value: item.Summary},
+		}
+		for _, category := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		if item.ContentHTML != "" {
+			entry.Content = &atomText{Type: "html", Value: item.ContentHTML}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return marshalXML(feed)
+}
+
+// JSON は JSON Feed 1.1 形式で出力する
+func (f Feed) JSON() ([]byte, error) {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.FeedURL,
+		Description: f.Description,
+		Language:    f.Language,
+		Items:       []jsonItem{},
+	}
+	for _, item := range f.Items {
+		entry := jsonItem{
+			ID:            item.Link,
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.ContentHTML,
+			Summary:       item.Summary,
+			DatePublished: rfc3339(item.Published),
+			DateModified:  rfc3339(item.Updated),
+			Tags:          item.Categories,
+		}
+		// content_html と content_text のどちらかは必須
+		if entry.ContentHTML == "" {
+			entry.ContentText = item.Summary
+		}
+		feed.Items = append(feed.Items, entry)
+	}
+
+	return json.Marshal(feed)
+}
+
+var stripPolicy = bluemonday.StrictPolicy()
+
+// Summarize はHTMLからタグを取り除き、maxRunes文字以内のプレーンテキストの要約を作る
+func Summarize(contentHTML string, maxRunes int) string {
+	text := html.UnescapeString(stripPolicy.Sanitize(contentHTML))
+	text = strings.Join(strings.Fields(text), " ")
+
+	if utf8.RuneCountInString(text) <= maxRunes {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:maxRunes])) + "…"
+}
+
+func marshalXML(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func rfc822(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC1123Z)
+}
+
+func rfc3339(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,131 @@
+// Package httpcache は読み出しのエンドポイントの条件付きGET (ETag / Last-Modified → 304) と
+// Cache-Control のポリシーを扱う
+//
+// Cache-Control はルートごとに CACHE_CONTROL 環境変数で上書きできる。
+// 形式は "{ルート}={ポリシー}" を ; で区切ったもの (ルートは API Gateway のリソースパス)。
+//
+//	CACHE_CONTROL="/posts=public, max-age=60;/posts/{id}=public, max-age=300, stale-while-revalidate=600"
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Validators: レスポンスの検証子
+type Validators struct {
+	ETag         string    // 強いETag ("..." の形式)
+	LastModified time.Time // ゼロ値の場合は Last-Modified を返さない
+}
+
+// ETag はレスポンスボディのハッシュから強いETagを作る
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Policies: ルートごとの Cache-Control
+type Policies map[string]string
+
+// ParsePolicies は CACHE_CONTROL の形式の文字列を読む (形式が誤っているエントリは無視する)
+func ParsePolicies(s string) Policies {
+	policies := Policies{}
+	for _, entry := range strings.Split(s, ";") {
+		route, policy, ok := strings.Cut(entry, "=")
+		route, policy = strings.TrimSpace(route), strings.TrimSpace(policy)
+		if !ok || route == "" || policy == "" {
+			continue
+		}
+		policies[route] = policy
+	}
+	return policies
+}
+
+// PoliciesFromEnv は CACHE_CONTROL 環境変数からポリシーを読む
+func PoliciesFromEnv() Policies {
+	return ParsePolicies(os.Getenv("CACHE_CONTROL"))
+}
+
+// For はルートの Cache-Control を返す (設定されていない場合は fallback)
+func (p Policies) For(route, fallback string) string {
+	if policy, ok := p[route]; ok {
+		return policy
+	}
+	return fallback
+}
+
+// Route はリクエストのルート (API Gateway のリソースパス、無い場合はパス) を返す
+func Route(request events.APIGatewayProxyRequest) string {
+	if request.Resource != "" {
+		return request.Resource
+	}
+	return request.Path
+}
+
+// Header はヘッダーを大文字小文字を区別せずに取得する
+func Header(request events.APIGatewayProxyRequest, name string) string {
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// NotModified は条件付きリクエスト (If-None-Match / If-Modified-Since) に対して304を返せるかを判定する
+// If-None-Match がある場合は If-Modified-Since を見ない (RFC 9110)
+func NotModified(request events.APIGatewayProxyRequest, v Validators) bool {
+	if inm := Header(request, "If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || (v.ETag != "" && candidate == v.ETag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := Header(request, "If-Modified-Since"); ims != "" && !v.LastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !v.LastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// Respond は検証子と Cache-Control をヘッダーに付けて200を返す
+// 条件付きリクエストに一致した場合はボディを付けずに304を返す
+func Respond(request events.APIGatewayProxyRequest, headers map[string]string, body string, v Validators, cacheControl string) events.APIGatewayProxyResponse {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	if v.ETag != "" {
+		headers["ETag"] = v.ETag
+	}
+	if !v.LastModified.IsZero() {
+		headers["Last-Modified"] = v.LastModified.UTC().Format(http.TimeFormat)
+	}
+	if cacheControl != "" {
+		headers["Cache-Control"] = cacheControl
+	}
+
+	if NotModified(request, v) {
+		return events.APIGatewayProxyResponse{StatusCode: 304, Headers: headers}
+	}
+	return events.APIGatewayProxyResponse{StatusCode: 200, Headers: headers, Body: body}
+}
+
+// LastModified は RFC 3339 の日時を Last-Modified に使う時刻にする (空や不正な値はゼロ値)
+func LastModified(rfc3339 string) time.Time {
+	t, err := time.Parse(time.RFC3339, rfc3339)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
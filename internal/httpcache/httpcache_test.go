@@ -0,0 +1,119 @@
+package httpcache
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var lastModified = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func request(headers map[string]string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{Headers: headers}
+}
+
+func TestNotModified(t *testing.T) {
+	v := Validators{ETag: `"abc"`, LastModified: lastModified.Add(500 * time.Millisecond)}
+	since := lastModified.Format(http.TimeFormat)
+	before := lastModified.Add(-time.Second).Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		v       Validators
+		want    bool
+	}{
+		{"no conditions", nil, v, false},
+		{"etag match", map[string]string{"If-None-Match": `"abc"`}, v, true},
+		{"header name is case-insensitive", map[string]string{"if-none-match": `"abc"`}, v, true},
+		{"weak etag matches", map[string]string{"If-None-Match": `W/"abc"`}, v, true},
+		{"etag in list", map[string]string{"If-None-Match": `"x", W/"abc" , "y"`}, v, true},
+		{"etag mismatch", map[string]string{"If-None-Match": `"xyz"`}, v, false},
+		{"star", map[string]string{"If-None-Match": "*"}, v, true},
+		{"star without etag", map[string]string{"If-None-Match": "*"}, Validators{}, true},
+		{"empty etag never matches", map[string]string{"If-None-Match": `""`}, Validators{}, false},
+		{"if-none-match takes precedence over a matching date", map[string]string{"If-None-Match": `"xyz"`, "If-Modified-Since": since}, v, false},
+		{"if-none-match takes precedence over an old date", map[string]string{"If-None-Match": `"abc"`, "If-Modified-Since": before}, v, true},
+		{"not modified since (sub-second truncated)", map[string]string{"If-Modified-Since": since}, v, true},
+		{"modified since", map[string]string{"If-Modified-Since": before}, v, false},
+		{"invalid date", map[string]string{"If-Modified-Since": "yesterday"}, v, false},
+		{"no last-modified", map[string]string{"If-Modified-Since": since}, Validators{ETag: `"abc"`}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NotModified(request(tt.headers), tt.v); got != tt.want {
+				t.Errorf("NotModified(%v) = %v, want %v", tt.headers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespond(t *testing.T) {
+	v := Validators{ETag: `"abc"`, LastModified: lastModified}
+
+	ok := Respond(request(nil), map[string]string{"Content-Type": "application/json"}, "{}", v, "no-cache")
+	if ok.StatusCode != 200 || ok.Body != "{}" {
+		t.Errorf("Respond = %d %q, want 200 {}", ok.StatusCode, ok.Body)
+	}
+	want := map[string]string{
+		"Content-Type":  "application/json",
+		"ETag":          `"abc"`,
+		"Last-Modified": "Wed, 01 May 2024 12:00:00 GMT",
+		"Cache-Control": "no-cache",
+	}
+	for name, value := range want {
+		if ok.Headers[name] != value {
+			t.Errorf("header %s = %q, want %q", name, ok.Headers[name], value)
+		}
+	}
+
+	notModified := Respond(request(map[string]string{"If-None-Match": `"abc"`}), nil, "{}", v, "no-cache")
+	if notModified.StatusCode != 304 || notModified.Body != "" {
+		t.Errorf("Respond = %d %q, want 304 without body", notModified.StatusCode, notModified.Body)
+	}
+	if notModified.Headers["ETag"] != `"abc"` || notModified.Headers["Cache-Control"] != "no-cache" {
+		t.Errorf("304 headers = %v, want ETag and Cache-Control", notModified.Headers)
+	}
+
+	etagOnly := Respond(request(nil), nil, "{}", Validators{ETag: `"abc"`}, "")
+	if _, ok := etagOnly.Headers["Last-Modified"]; ok {
+		t.Error("Last-Modified is set for a zero time")
+	}
+	if _, ok := etagOnly.Headers["Cache-Control"]; ok {
+		t.Error("Cache-Control is set for an empty policy")
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	p := ParsePolicies(" /posts = public, max-age=60 ;/posts/{id}=no-cache;broken;=x;/empty=")
+	tests := []struct {
+		route string
+		want  string
+	}{
+		{"/posts", "public, max-age=60"},
+		{"/posts/{id}", "no-cache"},
+		{"/empty", "fallback"},
+		{"/unknown", "fallback"},
+	}
+	for _, tt := range tests {
+		if got := p.For(tt.route, "fallback"); got != tt.want {
+			t.Errorf("For(%q) = %q, want %q", tt.route, got, tt.want)
+		}
+	}
+	if len(p) != 2 {
+		t.Errorf("ParsePolicies kept %d entries, want 2: %v", len(p), p)
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	if got := LastModified("2024-05-01T12:00:00Z"); !got.Equal(lastModified) {
+		t.Errorf("LastModified = %v, want %v", got, lastModified)
+	}
+	for _, value := range []string{"", "2024-05-01"} {
+		if got := LastModified(value); !got.IsZero() {
+			t.Errorf("LastModified(%q) = %v, want zero", value, got)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+// Package idempotency は Idempotency-Key ヘッダーによる再試行の重複実行の防止を扱う
+//
+// 最初のリクエストの処理中は記録テーブル (IDEMPOTENCY_TABLE_NAME) に in_progress のレコードを置き、
+// 完了したらレスポンスを保存する。同じキーで再試行されたリクエストには保存したレスポンスをそのまま返す。
+// 同じキーで内容の異なるリクエストは 422 とする。レコードは DynamoDB の TTL (既定で24時間) で削除される。
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/clock"
+)
+
+const (
+	// DefaultTTL: レコードを保持する期間 (この間は同じキーの再試行に最初のレスポンスを返す)
+	DefaultTTL = 24 * time.Hour
+	// LockTimeout: in_progress のレコードを処理中とみなす期間
+	// Lambdaの最大実行時間を過ぎても完了していないレコードは、途中で異常終了したものとして引き継ぐ
+	LockTimeout = 15 * time.Minute
+
+	// maxKeyLength: Idempotency-Key の最大長
+	maxKeyLength = 255
+)
+
+const (
+	statusInProgress = "in_progress"
+	statusCompleted  = "completed"
+)
+
+var (
+	// ErrInvalidKey は Idempotency-Key ヘッダーの値が不正な場合のエラー (400)
+	ErrInvalidKey = errors.New("invalid Idempotency-Key header")
+	// ErrMismatch は同じキーで内容の異なるリクエストが送られた場合のエラー (422)
+	ErrMismatch = errors.New("Idempotency-Key was already used for a different request")
+	// ErrInProgress は同じキーのリクエストがまだ処理中の場合のエラー (409)
+	ErrInProgress = errors.New("a request with the same Idempotency-Key is still in progress")
+)
+
+// Record: 記録テーブルに保存するデータ構造
+type Record struct {
+	Key             string            `dynamodbav:"key"` // {scope}:{Idempotency-Key}
+	RequestHash     string            `dynamodbav:"requestHash"`
+	Status          string            `dynamodbav:"status"`
+	LockedUntil     int64             `dynamodbav:"lockedUntil,omitempty"` // in_progress の場合のみ
+	StatusCode      int               `dynamodbav:"statusCode,omitempty"`
+	Headers         map[string]string `dynamodbav:"headers,omitempty"`
+	Body            string            `dynamodbav:"body,omitempty"`
+	IsBase64Encoded bool              `dynamodbav:"isBase64Encoded,omitempty"`
+	CreatedAt       string            `dynamodbav:"createdAt"`
+	TTL             int64             `dynamodbav:"ttl"`
+}
+
+// Store は記録テーブルを使って Idempotency-Key の付いたリクエストを1回だけ処理する
+type Store struct {
+	Client *dynamodb.Client
+	Table  string
+	TTL    time.Duration // 0 の場合は DefaultTTL
+	Clock  clock.Clock   // nil の場合は clock.System
+}
+
+// Key はリクエストの Idempotency-Key ヘッダーを返す (無い場合は空)
+func Key(request events.APIGatewayProxyRequest) (string, error) {
+	var key string
+	for name, v := range request.Headers {
+		if strings.EqualFold(name, "Idempotency-Key") {
+			key = strings.TrimSpace(v)
+			break
+		}
+	}
+	if len(key) > maxKeyLength {
+		return "", ErrInvalidKey
+	}
+	for _, r := range key {
+		if r < 0x21 || r > 0x7e {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
+
+// Fingerprint はリクエストの内容 (メソッド・パス・ボディ) のハッシュを返す
+// multipart の boundary は再試行のたびに変わりうるため、ボディから除いてから比べる
+func Fingerprint(request events.APIGatewayProxyRequest) string {
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		if decoded, err := base64.StdEncoding.DecodeString(request.Body); err == nil {
+			body = decoded
+		}
+	}
+
+	for name, v := range request.Headers {
+		if !strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		if _, params, err := mime.ParseMediaType(v); err == nil && params["boundary"] != "" {
+			body = bytes.ReplaceAll(body, []byte(params["boundary"]), nil)
+		}
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", request.HTTPMethod, request.Path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Do は Idempotency-Key の付いたリクエストを1回だけ handler で処理する
+// scope はキーの名前空間 (エンドポイントごとに分ける)。キーが無い場合やテーブルが未設定の場合は毎回 handler を呼ぶ
+// テーブルが未設定のままキーの付いたリクエストを受けた場合は、重複実行を防げないことをログに残す
+// 5xx のレスポンスは保存せず、同じキーで再試行できるようにする
+func (s *Store) Do(ctx context.Context, scope string, request events.APIGatewayProxyRequest, handler func() (events.APIGatewayProxyResponse, error)) (events.APIGatewayProxyResponse, error) {
+	key, err := Key(request)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: err.Error()}, nil
+	}
+	if key == "" {
+		return handler()
+	}
+	if s.Table == "" {
+		fmt.Printf("Warning: idempotency table is not configured, processing Idempotency-Key %s without deduplication\n", key)
+		return handler()
+	}
+	recordKey := scope + ":" + key
+
+	replay, err := s.begin(ctx, recordKey, Fingerprint(request))
+	switch {
+	case errors.Is(err, ErrMismatch):
+		return events.APIGatewayProxyResponse{StatusCode: 422, Body: fmt.Sprintf("Idempotency-Key %s was already used for a different request", key)}, nil
+	case errors.Is(err, ErrInProgress):
+		return events.APIGatewayProxyResponse{StatusCode: 409, Body: fmt.Sprintf("A request with Idempotency-Key %s is still in progress", key)}, nil
+	case err != nil:
+		fmt.Printf("Error checking idempotency key %s: %v\n", recordKey, err)
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: fmt.Sprintf("Failed to check idempotency key: %v", err)}, nil
+	}
+	if replay != nil {
+		fmt.Printf("Replaying response for idempotency key %s\n", recordKey)
+		return *replay, nil
+	}
+
+	response, err := handler()
+	// 処理がタイムアウトでキャンセルされていても記録は更新する
+	recordCtx := context.WithoutCancel(ctx)
+	if err != nil || response.StatusCode >= 500 {
+		if releaseErr := s.release(recordCtx, recordKey); releaseErr != nil {
+			fmt.Printf("Error releasing idempotency key %s: %v\n", recordKey, releaseErr)
+		}
+		return response, err
+	}
+	if completeErr := s.complete(recordCtx, recordKey, response); completeErr != nil {
+		// 処理自体は成功しているので、ここではエラーを返さない (ログは出す)
+		fmt.Printf("Error saving response for idempotency key %s: %v\n", recordKey, completeErr)
+	}
+	return response, nil
+}
+
+// begin はレコードを in_progress で作成する
+// 既に完了したレコードがある場合は保存したレスポンスを返す
+func (s *Store) begin(ctx context.Context, recordKey, hash string) (*events.APIGatewayProxyResponse, error) {
+	now := s.now()
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	av, err := attributevalue.MarshalMap(Record{
+		Key:         recordKey,
+		RequestHash: hash,
+		Status:      statusInProgress,
+		LockedUntil: now.Add(LockTimeout).Unix(),
+		CreatedAt:   now.UTC().Format(time.RFC3339),
+		TTL:         now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 新しいキー、TTLを過ぎた (DynamoDBによる削除前の) レコード、異常終了した同じリクエストのレコードの場合だけ作成する
+	_, err = s.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.Table),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(#key) OR #ttl <= :now OR (#status = :inProgress AND lockedUntil <= :now AND requestHash = :hash)"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":    "key",
+			"#ttl":    "ttl",
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":        &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			":inProgress": &types.AttributeValueMemberS{Value: statusInProgress},
+			":hash":       &types.AttributeValueMemberS{Value: hash},
+		},
+	})
+	if err == nil {
+		return nil, nil
+	}
+	var conditionFailed *types.ConditionalCheckFailedException
+	if !errors.As(err, &conditionFailed) {
+		return nil, err
+	}
+
+	record, err := s.get(ctx, recordKey)
+	if err != nil {
+		return nil, err
+	}
+	return replay(record, hash)
+}
+
+// replay は既にあるレコードから再試行に返すレスポンスを作る
+//   - 内容の異なるリクエストのレコード: ErrMismatch
+//   - 処理中のレコード (読み直すまでの間に解放された場合も含む): ErrInProgress
+//   - 完了したレコード: 保存したレスポンス (Idempotent-Replayed ヘッダーを付ける)
+func replay(record *Record, hash string) (*events.APIGatewayProxyResponse, error) {
+	if record == nil {
+		return nil, ErrInProgress
+	}
+	if record.RequestHash != hash {
+		return nil, ErrMismatch
+	}
+	if record.Status != statusCompleted {
+		return nil, ErrInProgress
+	}
+
+	headers := map[string]string{}
+	for k, v := range record.Headers {
+		headers[k] = v
+	}
+	headers["Idempotent-Replayed"] = "true"
+	return &events.APIGatewayProxyResponse{
+		StatusCode:      record.StatusCode,
+		Headers:         headers,
+		Body:            record.Body,
+		IsBase64Encoded: record.IsBase64Encoded,
+	}, nil
+}
+
+// complete はレスポンスを保存してレコードを完了にする
+func (s *Store) complete(ctx context.Context, recordKey string, response events.APIGatewayProxyResponse) error {
+	headers, err := attributevalue.Marshal(response.Headers)
+	if err != nil {
+		return err
+	}
+	if len(response.Headers) == 0 {
+		headers = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}}
+	}
+
+	_, err = s.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.Table),
+		Key: map[string]types.AttributeValue{
+			"key": &types.AttributeValueMemberS{Value: recordKey},
+		},
+		UpdateExpression: aws.String("SET #status = :completed, statusCode = :statusCode, #headers = :headers, #body = :body, isBase64Encoded = :isBase64Encoded REMOVE lockedUntil"),
+		ExpressionAttributeNames: map[string]string{
+			"#status":  "status",
+			"#headers": "headers",
+			"#body":    "body",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":completed":       &types.AttributeValueMemberS{Value: statusCompleted},
+			":statusCode":      &types.AttributeValueMemberN{Value: strconv.Itoa(response.StatusCode)},
+			":headers":         headers,
+			":body":            &types.AttributeValueMemberS{Value: response.Body},
+			":isBase64Encoded": &types.AttributeValueMemberBOOL{Value: response.IsBase64Encoded},
+		},
+	})
+	return err
+}
+
+// release は処理中のレコードを削除し、同じキーで再試行できるようにする
+func (s *Store) release(ctx context.Context, recordKey string) error {
+	_, err := s.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.Table),
+		Key: map[string]types.AttributeValue{
+			"key": &types.AttributeValueMemberS{Value: recordKey},
+		},
+		ConditionExpression:      aws.String("#status = :inProgress"),
+		ExpressionAttributeNames: map[string]string{"#status": "status"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":inProgress": &types.AttributeValueMemberS{Value: statusInProgress},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+	return err
+}
+
+func (s *Store) get(ctx context.Context, recordKey string) (*Record, error) {
+	result, err := s.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.Table),
+		Key: map[string]types.AttributeValue{
+			"key": &types.AttributeValueMemberS{Value: recordKey},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var record Record
+	if err := attributevalue.UnmarshalMap(result.Item, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *Store) now() time.Time {
+	if s.Clock == nil {
+		return clock.System.Now()
+	}
+	return s.Clock.Now()
+}
@@ -0,0 +1,171 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/clock"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+		wantErr error
+	}{
+		{"missing", nil, "", nil},
+		{"uuid", map[string]string{"Idempotency-Key": "5f0c8a2e-4d7b-4f7e-9a51-2f6b8c1d3e90"}, "5f0c8a2e-4d7b-4f7e-9a51-2f6b8c1d3e90", nil},
+		{"header name is case-insensitive", map[string]string{"idempotency-key": "abc"}, "abc", nil},
+		{"surrounding spaces", map[string]string{"Idempotency-Key": " abc "}, "abc", nil},
+		{"max length", map[string]string{"Idempotency-Key": strings.Repeat("a", maxKeyLength)}, strings.Repeat("a", maxKeyLength), nil},
+		{"too long", map[string]string{"Idempotency-Key": strings.Repeat("a", maxKeyLength+1)}, "", ErrInvalidKey},
+		{"inner space", map[string]string{"Idempotency-Key": "a b"}, "", ErrInvalidKey},
+		{"non-ascii", map[string]string{"Idempotency-Key": "キー"}, "", ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Key(events.APIGatewayProxyRequest{Headers: tt.headers})
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("Key = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// multipart はアップロードのリクエストを boundary を変えて作る
+func multipart(boundary string, base64Encoded bool) events.APIGatewayProxyRequest {
+	body := "--" + boundary + "\r\nContent-Disposition: form-data; name=\"title\"\r\n\r\nHello\r\n--" + boundary + "--\r\n"
+	if base64Encoded {
+		body = base64.StdEncoding.EncodeToString([]byte(body))
+	}
+	return events.APIGatewayProxyRequest{
+		HTTPMethod:      "POST",
+		Path:            "/drafts",
+		Headers:         map[string]string{"content-type": "multipart/form-data; boundary=" + boundary},
+		Body:            body,
+		IsBase64Encoded: base64Encoded,
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := multipart("boundary-1", false)
+	json := events.APIGatewayProxyRequest{HTTPMethod: "POST", Path: "/posts", Body: `{"id":"a"}`}
+
+	tests := []struct {
+		name string
+		a, b events.APIGatewayProxyRequest
+		same bool
+	}{
+		{"same request", base, multipart("boundary-1", false), true},
+		{"different boundary", base, multipart("boundary-2", false), true},
+		{"base64 encoded", base, multipart("boundary-3", true), true},
+		{"different path", json, events.APIGatewayProxyRequest{HTTPMethod: "POST", Path: "/drafts", Body: `{"id":"a"}`}, false},
+		{"different method", json, events.APIGatewayProxyRequest{HTTPMethod: "PUT", Path: "/posts", Body: `{"id":"a"}`}, false},
+		{"different body", json, events.APIGatewayProxyRequest{HTTPMethod: "POST", Path: "/posts", Body: `{"id":"b"}`}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fingerprint(tt.a) == Fingerprint(tt.b); got != tt.same {
+				t.Errorf("Fingerprint equal = %v, want %v", got, tt.same)
+			}
+		})
+	}
+
+	changed := multipart("boundary-4", false)
+	changed.Body = strings.Replace(changed.Body, "Hello", "World", 1)
+	if Fingerprint(base) == Fingerprint(changed) {
+		t.Error("Fingerprint ignores the form content")
+	}
+}
+
+func TestDoWithoutRecord(t *testing.T) {
+	// キーが無い場合・テーブルが未設定の場合は記録テーブルを使わずに毎回処理する
+	tests := []struct {
+		name      string
+		store     *Store
+		headers   map[string]string
+		wantCode  int
+		wantCalls int
+	}{
+		{"no key", &Store{Table: "idempotency"}, nil, 201, 1},
+		{"no table", &Store{}, map[string]string{"Idempotency-Key": "abc"}, 201, 1},
+		{"invalid key", &Store{Table: "idempotency"}, map[string]string{"Idempotency-Key": "a b"}, 400, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			for range 2 {
+				response, err := tt.store.Do(context.Background(), "test", events.APIGatewayProxyRequest{Headers: tt.headers}, func() (events.APIGatewayProxyResponse, error) {
+					calls++
+					return events.APIGatewayProxyResponse{StatusCode: 201}, nil
+				})
+				if err != nil || response.StatusCode != tt.wantCode {
+					t.Fatalf("Do = %d, %v, want %d", response.StatusCode, err, tt.wantCode)
+				}
+			}
+			if calls != 2*tt.wantCalls {
+				t.Errorf("handler called %d times, want %d", calls, 2*tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestStoreNow(t *testing.T) {
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if got := (&Store{Clock: clock.Fixed(fixed)}).now(); !got.Equal(fixed) {
+		t.Errorf("now = %v, want %v", got, fixed)
+	}
+	if got := (&Store{}).now(); got.IsZero() {
+		t.Error("now without a clock is zero")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	completed := &Record{
+		RequestHash: "hash",
+		Status:      statusCompleted,
+		StatusCode:  201,
+		Headers:     map[string]string{"Content-Type": "application/json", "ETag": `"1"`},
+		Body:        `{"id":"a"}`,
+	}
+	tests := []struct {
+		name     string
+		record   *Record
+		hash     string
+		wantErr  error
+		wantCode int
+	}{
+		{"completed", completed, "hash", nil, 201},
+		{"different request", completed, "other", ErrMismatch, 0},
+		{"in progress", &Record{RequestHash: "hash", Status: statusInProgress}, "hash", ErrInProgress, 0},
+		{"different request in progress", &Record{RequestHash: "hash", Status: statusInProgress}, "other", ErrMismatch, 0},
+		{"released", nil, "hash", ErrInProgress, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := replay(tt.record, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("replay error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if response.StatusCode != tt.wantCode || response.Body != completed.Body {
+				t.Errorf("replay = %d %s", response.StatusCode, response.Body)
+			}
+			if response.Headers["Idempotent-Replayed"] != "true" || response.Headers["ETag"] != `"1"` {
+				t.Errorf("replay headers = %v", response.Headers)
+			}
+		})
+	}
+	if _, ok := completed.Headers["Idempotent-Replayed"]; ok {
+		t.Error("replay modified the stored headers")
+	}
+}
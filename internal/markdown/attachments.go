@@ -0,0 +1,110 @@
+package markdown
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// Attachment: 本文から参照される添付ファイルと、その公開URL
+type Attachment struct {
+	Name     string    // ファイル名 (本文中では ![](example.png) のようにこの名前で参照される)
+	URL      string    // 参照を置き換える先のURL
+	Variants []Variant // レスポンシブ画像用の縮小版 (存在する場合のみ)
+}
+
+// Variant: 添付画像の幅違いの縮小版
+type Variant struct {
+	URL   string
+	Width int // px
+}
+
+var attachmentsKey = parser.NewContextKey()
+
+// attachmentResolver は1回のレンダリングの間、添付ファイルの対応表と警告を保持する
+type attachmentResolver struct {
+	byName   map[string]Attachment
+	warnings []string
+}
+
+// attachmentTransformer は画像・リンクの相対参照を添付ファイルのURLに書き換える
+type attachmentTransformer struct{}
+
+func (attachmentTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	resolver, ok := pc.Get(attachmentsKey).(*attachmentResolver)
+	if !ok {
+		return
+	}
+
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.Image:
+			if attachment, ok := resolver.resolve("image", n.Destination); ok {
+				n.Destination = []byte(attachment.URL)
+				if srcset := srcset(attachment); srcset != "" {
+					n.SetAttributeString("srcset", []byte(srcset))
+				}
+			}
+		case *ast.Link:
+			if attachment, ok := resolver.resolve("link", n.Destination); ok {
+				n.Destination = []byte(attachment.URL)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// resolve は相対参照に一致する添付ファイルを返す
+// 相対参照なのに一致する添付ファイルが無い場合は警告を記録する
+func (r *attachmentResolver) resolve(kind string, destination []byte) (Attachment, bool) {
+	name, ok := relativeReference(string(destination))
+	if !ok {
+		return Attachment{}, false
+	}
+
+	attachment, found := r.byName[name]
+	if !found {
+		r.warnings = append(r.warnings, fmt.Sprintf("%s reference %q does not match any attachment", kind, string(destination)))
+	}
+	return attachment, found
+}
+
+// relativeReference は参照先がページ内アンカーや絶対URLではない場合に、パスを正規化して返す
+func relativeReference(destination string) (string, bool) {
+	if destination == "" || strings.HasPrefix(destination, "#") || strings.HasPrefix(destination, "/") {
+		return "", false
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+
+	return path.Clean(strings.TrimPrefix(u.Path, "./")), true
+}
+
+// srcset は縮小版を幅の昇順に並べた srcset 属性の値を返す
+func srcset(attachment Attachment) string {
+	if len(attachment.Variants) == 0 {
+		return ""
+	}
+
+	variants := append([]Variant(nil), attachment.Variants...)
+	sort.Slice(variants, func(i, j int) bool { return variants[i].Width < variants[j].Width })
+
+	candidates := make([]string, 0, len(variants))
+	for _, v := range variants {
+		candidates = append(candidates, v.URL+" "+strconv.Itoa(v.Width)+"w")
+	}
+	return strings.Join(candidates, ", ")
+}
@@ -0,0 +1,62 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+)
+
+// headingIDs は見出しのアンカーIDを生成する
+// goldmark標準の実装はASCII以外の文字を落とすため、日本語の見出しでも読めるIDになるよう独自に実装している
+type headingIDs struct {
+	used map[string]bool
+}
+
+func newHeadingIDs() parser.IDs {
+	return &headingIDs{used: map[string]bool{}}
+}
+
+// Generate は見出しのテキストからIDを作り、同じ文書内で重複する場合は "-1", "-2" ... を付ける
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	base := slugify(string(value))
+	if base == "" {
+		base = "heading"
+	}
+
+	id := base
+	for i := 1; s.used[id]; i++ {
+		id = base + "-" + strconv.Itoa(i)
+	}
+	s.used[id] = true
+
+	return []byte(id)
+}
+
+// Put は明示的に指定されたID ({#id} 記法など) を使用済みとして登録する
+func (s *headingIDs) Put(value []byte) {
+	s.used[string(value)] = true
+}
+
+// slugify は小文字化した上で、文字・数字以外を取り除き空白をハイフンに置き換える
+func slugify(text string) string {
+	var b strings.Builder
+	pendingHyphen := false
+
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_':
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-':
+			pendingHyphen = true
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,79 @@
+// Package markdown は記事本文 (Markdown) をサーバーサイドでHTMLに変換する
+//
+// GFM (テーブル・タスクリスト・取り消し線・自動リンク) と脚注、Chromaによるシンタックスハイライト、
+// 見出しへのアンカーIDの付与に対応し、出力は許可リスト方式のサニタイザを通してから返す。
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// highlightStyle: コードブロックに使うChromaのスタイル名
+const highlightStyle = "github"
+
+var md = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM,
+		extension.Footnote,
+		extension.CJK,
+		highlighting.NewHighlighting(
+			highlighting.WithStyle(highlightStyle),
+			highlighting.WithFormatOptions(html.TabWidth(4)),
+		),
+	),
+	goldmark.WithParserOptions(
+		parser.WithAutoHeadingID(),
+		parser.WithASTTransformers(util.Prioritized(attachmentTransformer{}, 100)),
+	),
+	goldmark.WithRendererOptions(
+		// 本文中のHTMLはそのまま出力し、サニタイザで危険な要素・属性を取り除く
+		goldmarkhtml.WithUnsafe(),
+	),
+)
+
+// Options: レンダリングのオプション
+type Options struct {
+	// 本文中の相対参照 (![](example.png) や [資料](slides.pdf)) を解決する添付ファイル
+	Attachments []Attachment
+}
+
+// Result: レンダリング結果
+type Result struct {
+	HTML     string
+	Warnings []string  // どの添付ファイルにも一致しなかった参照など
+	TOC      []Heading // 見出しの目次 (IDはHTMLのアンカーIDと同じ)
+}
+
+// Render はMarkdownをサニタイズ済みのHTMLに変換する
+// 目次は同じ構文木から作るため、見出しのIDはHTMLと必ず一致する
+func Render(source string, opts Options) (Result, error) {
+	var buf bytes.Buffer
+
+	resolver := &attachmentResolver{byName: map[string]Attachment{}}
+	for _, attachment := range opts.Attachments {
+		resolver.byName[attachment.Name] = attachment
+	}
+
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	ctx.Set(attachmentsKey, resolver)
+	src := []byte(source)
+	doc := md.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
+	if err := md.Renderer().Render(&buf, src, doc); err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		HTML:     policy.Sanitize(buf.String()),
+		Warnings: resolver.warnings,
+		TOC:      outline(doc, src),
+	}, nil
+}
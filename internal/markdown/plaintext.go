@@ -0,0 +1,56 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// PlainText は本文から Markdown の記法を取り除いたテキストを返す
+// 段落内の改行は1つの改行、段落・見出しなどのブロックの区切りは2つの改行にする。コードブロックは prose に含めず code に入れる。
+// 画像と本文中のHTMLは取り除く。
+func PlainText(source string) (prose, code string) {
+	src := []byte(source)
+	doc := md.Parser().Parse(text.NewReader(src))
+
+	var p, c strings.Builder
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch n := node.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			if entering {
+				lines := n.Lines()
+				for i := 0; i < lines.Len(); i++ {
+					segment := lines.At(i)
+					c.Write(segment.Value(src))
+				}
+				c.WriteByte('\n')
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.Image, *ast.HTMLBlock, *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if entering {
+				p.Write(n.Segment.Value(src))
+				if n.SoftLineBreak() || n.HardLineBreak() {
+					p.WriteByte('\n')
+				}
+			}
+		case *ast.String:
+			if entering {
+				p.Write(n.Value)
+			}
+		case *ast.AutoLink:
+			if entering {
+				p.Write(n.Label(src))
+			}
+		default:
+			if !entering && n.Type() == ast.TypeBlock {
+				p.WriteString("\n\n")
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return p.String(), c.String()
+}
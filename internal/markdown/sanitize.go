@@ -0,0 +1,57 @@
+package markdown
+
+import (
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// policy: レンダリング結果に適用するHTMLの許可リスト
+// bluemondayのUGCPolicyをベースに、Markdownの拡張機能が出力する要素・属性だけを追加で許可する
+var policy = newPolicy()
+
+var (
+	anchorID     = regexp.MustCompile(`^[\p{L}\p{N}_:.\-]+$`)
+	footnoteRole = regexp.MustCompile(`^doc-(noteref|endnotes|backlink)$`)
+	footnoteCSS  = regexp.MustCompile(`^footnote-(ref|backref)$`)
+
+	// srcset の候補 ("URL 幅w" または "URL 倍率x") のカンマ区切り
+	// URL は http / https か、スキームの無い相対URLだけを許可する (src と同じく javascript: などを通さない)
+	srcsetValue = regexp.MustCompile(`^` + srcsetCandidate + `(?:,` + srcsetCandidate + `)*$`)
+)
+
+const srcsetCandidate = `\s*(?:(?i:https?://)[^\s,]+|/[^\s,]*|[\w.~%\-][^\s,:]*)(?:\s+\d+(?:\.\d+)?[wx])?\s*`
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+
+	// 見出しのアンカーID (日本語を含む) と脚注のID
+	p.AllowAttrs("id").Matching(anchorID).OnElements("h1", "h2", "h3", "h4", "h5", "h6", "li", "sup")
+
+	// 脚注
+	p.AllowAttrs("role").Matching(footnoteRole).OnElements("a", "div", "sup")
+	p.AllowAttrs("class").Matching(footnoteCSS).OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^footnotes$`)).OnElements("div")
+
+	// タスクリストのチェックボックス
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+
+	// レスポンシブ画像 (添付ファイルの縮小版)
+	p.AllowAttrs("srcset").Matching(srcsetValue).OnElements("img")
+	p.AllowAttrs("sizes").OnElements("img")
+
+	// テーブルの列揃え
+	p.AllowAttrs("style").OnElements("th", "td")
+	p.AllowStyles("text-align").MatchingEnum("left", "center", "right").OnElements("th", "td")
+
+	// Chromaのインラインスタイル
+	p.AllowAttrs("tabindex").Matching(regexp.MustCompile(`^0$`)).OnElements("pre")
+	p.AllowAttrs("style").OnElements("pre", "span")
+	p.AllowStyles("color", "background-color", "font-weight", "font-style", "text-decoration").OnElements("pre", "span")
+	p.AllowStyles("display").MatchingEnum("flex", "grid").OnElements("span")
+	p.AllowStyles("white-space").MatchingEnum("pre").OnElements("span")
+	p.AllowStyles("user-select", "margin-right", "padding", "-webkit-text-size-adjust").OnElements("span", "pre")
+
+	return p
+}
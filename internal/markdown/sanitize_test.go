@@ -0,0 +1,34 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeSrcset(t *testing.T) {
+	tests := []struct {
+		name   string
+		srcset string
+		keep   bool
+	}{
+		{"https", "https://cdn.example.com/a-480.webp 480w, https://cdn.example.com/a-960.webp 960w", true},
+		{"relative", "/images/a-480.webp 480w, images/a-960.webp 2x", true},
+		{"without descriptor", "https://cdn.example.com/a.webp", true},
+		{"javascript", "javascript:alert(1) 480w", false},
+		{"javascript in second candidate", "https://cdn.example.com/a.webp 480w, javascript:alert(1) 960w", false},
+		{"uppercase javascript", "JavaScript:alert(1)", false},
+		{"data", "data:image/svg+xml;base64,PHN2Zz4= 1x", false},
+		{"entity encoded scheme", "javascript&#58;alert(1)", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := policy.Sanitize(`<img src="https://cdn.example.com/a.webp" srcset="` + tt.srcset + `" alt="a">`)
+			if got := strings.Contains(html, "srcset="); got != tt.keep {
+				t.Errorf("srcset kept = %v, want %v (html: %s)", got, tt.keep, html)
+			}
+			if strings.Contains(strings.ToLower(html), "javascript") {
+				t.Errorf("javascript: URL survived sanitization: %s", html)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// Heading: 目次の1項目
+// ID はレンダリングしたHTMLの見出しのアンカーIDと同じ値になる
+type Heading struct {
+	Level    int       `json:"level" dynamodbav:"level"`
+	Text     string    `json:"text" dynamodbav:"text"`
+	ID       string    `json:"id" dynamodbav:"id"`
+	Children []Heading `json:"children,omitempty" dynamodbav:"children,omitempty"`
+}
+
+// outline は文書の見出しを階層構造の目次にする
+// 直前の見出しより深いレベルの見出しはその子にする (h2 の次の h4 のようにレベルが飛んでもよい)
+func outline(doc ast.Node, src []byte) []Heading {
+	var flat []Heading
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		n, ok := node.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		heading := Heading{Level: n.Level, Text: inlineText(n, src)}
+		if id, ok := n.AttributeString("id"); ok {
+			if b, ok := id.([]byte); ok {
+				heading.ID = string(b)
+			}
+		}
+		flat = append(flat, heading)
+		return ast.WalkSkipChildren, nil
+	})
+
+	var toc []Heading
+	for i := 0; i < len(flat); {
+		var headings []Heading
+		headings, i = nest(flat, i)
+		toc = append(toc, headings...)
+	}
+	return toc
+}
+
+// nest は flat[i:] のうち、level より深い見出しの連続を階層構造にする
+// 戻り値は作った目次と、処理しなかった最初の見出しの位置
+func nest(flat []Heading, i int) ([]Heading, int) {
+	var result []Heading
+	level := flat[i].Level
+	for i < len(flat) && flat[i].Level >= level {
+		heading := flat[i]
+		i++
+		if i < len(flat) && flat[i].Level > heading.Level {
+			heading.Children, i = nest(flat, i)
+		}
+		result = append(result, heading)
+	}
+	return result, i
+}
+
+// inlineText は見出しなどのインライン要素から記法を取り除いたテキストを返す
+func inlineText(node ast.Node, src []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(node, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(src))
+			if n.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *ast.Image, *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(b.String())
+}
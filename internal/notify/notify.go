@@ -0,0 +1,99 @@
+// Package notify は運用上の通知 (下書きの期限切れの予告など) の送り先を差し替えられるようにする
+//
+// 送り先は NOTIFIER 環境変数で選ぶ。
+//   - log (デフォルト): Lambdaのログに出力する
+//   - webhook: NOTIFY_WEBHOOK_URL へJSONをPOSTする (Slackの Incoming Webhook など)
+//   - email: NOTIFY_EMAIL_TO 宛のメールの内容をログに出力する (SESなどで送信するまでの代替)
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Message: 通知の内容
+type Message struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`              // 人が読むための本文
+	Payload any    `json:"payload,omitempty"` // 機械的に処理するためのデータ
+}
+
+// Notifier は通知を送る
+type Notifier interface {
+	Notify(ctx context.Context, message Message) error
+}
+
+// FromEnv は NOTIFIER 環境変数に応じたNotifierを返す
+func FromEnv() (Notifier, error) {
+	switch kind := os.Getenv("NOTIFIER"); kind {
+	case "", "log":
+		return Log{}, nil
+	case "webhook":
+		url := os.Getenv("NOTIFY_WEBHOOK_URL")
+		if url == "" {
+			return nil, fmt.Errorf("NOTIFY_WEBHOOK_URL is required for the webhook notifier")
+		}
+		return &Webhook{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}, nil
+	case "email":
+		to := os.Getenv("NOTIFY_EMAIL_TO")
+		if to == "" {
+			return nil, fmt.Errorf("NOTIFY_EMAIL_TO is required for the email notifier")
+		}
+		return Email{To: to}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", kind)
+	}
+}
+
+// Log はログに出力するNotifier
+type Log struct{}
+
+func (Log) Notify(ctx context.Context, message Message) error {
+	fmt.Printf("[notify] %s\n%s\n", message.Subject, message.Text)
+	return nil
+}
+
+// Webhook はメッセージをJSONとしてPOSTするNotifier
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *Webhook) Notify(ctx context.Context, message Message) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Email はメールの代替となるNotifier (送信せず、宛先・件名・本文をログに出力する)
+type Email struct {
+	To string
+}
+
+func (e Email) Notify(ctx context.Context, message Message) error {
+	fmt.Printf("[notify:email] To: %s\nSubject: %s\n\n%s\n", e.To, message.Subject, message.Text)
+	return nil
+}
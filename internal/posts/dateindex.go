@@ -0,0 +1,254 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/projection"
+	"github.com/sunshine-724/my-homepage-backend/internal/timestamp"
+)
+
+// 日付のインデックス: 投稿テーブルのGSI (パーティションキー listing、ソートキー listingDate)
+// 公開中の記事にだけ listing = ListingPublished を付け、非公開・アーカイブ済みの記事には付けない (スパースインデックス)
+// date は著者が入力する自由形式の文字列のため、ソートキーには ListingDate で YYYY-MM-DD に揃えた listingDate を使う
+// GSI の射影は INCLUDE で summaryAttributes (id と listingDate 以外) を含める
+const (
+	DefaultDateIndex = "listing-date-index" // DATE_INDEX_NAME が未設定の場合のインデックス名
+	ListingPublished = "published"          // 公開中の記事の listing の値
+)
+
+// summaryAttributes: 日付のインデックスから読み出す属性
+var summaryAttributes = []string{"id", "title", "slug", "date", "listingDate", "tags", "excerpt", "readingTime", "expireAt"}
+
+// monthAttributes: 年・月ごとに数えるときに読み出す属性 (記事の概要は読まない)
+var monthAttributes = []string{"id", "listingDate", "expireAt"}
+
+// listingDateLayouts: ListingDate が解釈する日付の書式 (RFC 3339 以外)
+var listingDateLayouts = []string{
+	time.DateOnly,
+	"2006/01/02",
+	"2006.01.02",
+	"2006-1-2",
+	"2006/1/2",
+	"2006.1.2",
+	"2006年1月2日",
+}
+
+// ListingDate は日付のインデックスのソートキー (YYYY-MM-DD) を返す
+//   - date が RFC 3339 の日時の場合は、表示用のタイムゾーンでの日付
+//   - date が YYYY-MM-DD・YYYY/M/D・YYYY年M月D日 などの場合は、その日付
+//   - それ以外 (「2024年春」など) は最初に公開した日時 (publishedAt) の日付
+//
+// どちらからも日付が分からない場合は空文字列を返す (インデックスには載せない)
+func ListingDate(date, publishedAt string) string {
+	date = strings.TrimSpace(date)
+	if t, err := time.Parse(time.RFC3339, date); err == nil {
+		return timestamp.DisplayDate(t)
+	}
+	for _, layout := range listingDateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.Format(time.DateOnly)
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, publishedAt); err == nil {
+		return timestamp.DisplayDate(t)
+	}
+	return ""
+}
+
+// Summary: 日付のインデックスから読み出した記事の概要
+type Summary struct {
+	ID          string   `json:"id" dynamodbav:"id"`
+	Title       string   `json:"title" dynamodbav:"title"`
+	Slug        string   `json:"slug,omitempty" dynamodbav:"slug,omitempty"`
+	Date        string   `json:"date" dynamodbav:"date"`
+	ListingDate string   `json:"-" dynamodbav:"listingDate"`
+	Tags        []string `json:"tags" dynamodbav:"tags"`
+	Excerpt     string   `json:"excerpt,omitempty" dynamodbav:"excerpt,omitempty"`
+	ReadingTime int      `json:"readingTime,omitempty" dynamodbav:"readingTime,omitempty"`
+	ExpireAt    string   `json:"-" dynamodbav:"expireAt,omitempty"`
+}
+
+// Year: 年ごとの記事数
+type Year struct {
+	Year   int     `json:"year"`
+	Count  int     `json:"count"`
+	Months []Month `json:"months"` // 新しい月から順に並べる
+}
+
+// Month: 月ごとの記事数
+type Month struct {
+	Month int `json:"month"`
+	Count int `json:"count"`
+}
+
+// DateIndex は日付のインデックスを読み出す
+// 公開終了の日時を過ぎた記事 (アーカイブ待ち) はインデックスに残っているため、読み出した後に取り除く
+type DateIndex struct {
+	Client    *dynamodb.Client
+	TableName string
+	IndexName string
+}
+
+// Neighbors は一覧 (日付の新しい順、同じ日付はIDの順) で記事の前後にある公開中の記事を返す
+// date は記事の listingDate、older は1つ古い記事、newer は1つ新しい記事 (無い場合は nil)
+func (d DateIndex) Neighbors(ctx context.Context, id, date string, now time.Time) (older, newer *Summary, err error) {
+	// 古い方: date 以前を新しい順に読み、同じ日付ではIDが大きいものだけを候補にする
+	older, err = d.neighbor(ctx, "#listingDate <= :date", date, false, now, func(s Summary) bool {
+		return s.ListingDate < date || s.ID > id
+	}, func(s, best Summary) bool { return s.ID < best.ID })
+	if err != nil {
+		return nil, nil, err
+	}
+	// 新しい方: date 以後を古い順に読み、同じ日付ではIDが小さいものだけを候補にする
+	newer, err = d.neighbor(ctx, "#listingDate >= :date", date, true, now, func(s Summary) bool {
+		return s.ListingDate > date || s.ID < id
+	}, func(s, best Summary) bool { return s.ID > best.ID })
+	if err != nil {
+		return nil, nil, err
+	}
+	return older, newer, nil
+}
+
+// neighbor は条件に一致する記事を日付の順に読み、candidate を満たす最初の日付の記事のうち better で最も良いものを返す
+// ソートキーが同じ記事の順序は決まっていないため、同じ日付の記事は最後まで読んで比べる
+func (d DateIndex) neighbor(ctx context.Context, condition, date string, forward bool, now time.Time, candidate func(Summary) bool, better func(s, best Summary) bool) (*Summary, error) {
+	var best *Summary
+	err := d.query(ctx, summaryAttributes, condition, map[string]types.AttributeValue{
+		":date": &types.AttributeValueMemberS{Value: date},
+	}, forward, now, func(s Summary) bool {
+		if best != nil && s.ListingDate != best.ListingDate {
+			return false
+		}
+		if candidate(s) && (best == nil || better(s, *best)) {
+			best = &s
+		}
+		return true
+	})
+	return best, err
+}
+
+// Months は公開中の記事の数を年・月ごとに数える (新しい年・月から順に並べる)
+// 数えるのに必要な listingDate と expireAt だけを読み出す
+func (d DateIndex) Months(ctx context.Context, now time.Time) ([]Year, error) {
+	counts := map[int]map[int]int{}
+	err := d.query(ctx, monthAttributes, "", nil, false, now, func(s Summary) bool {
+		year, month, ok := yearMonth(s.ListingDate)
+		if !ok {
+			fmt.Printf("Skipping post %s with unrecognized listing date %q\n", s.ID, s.ListingDate)
+			return true
+		}
+		if counts[year] == nil {
+			counts[year] = map[int]int{}
+		}
+		counts[year][month]++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	years := []Year{}
+	for year, months := range counts {
+		y := Year{Year: year, Months: []Month{}}
+		for month, count := range months {
+			y.Months = append(y.Months, Month{Month: month, Count: count})
+			y.Count += count
+		}
+		sort.Slice(y.Months, func(i, j int) bool { return y.Months[i].Month > y.Months[j].Month })
+		years = append(years, y)
+	}
+	sort.Slice(years, func(i, j int) bool { return years[i].Year > years[j].Year })
+	return years, nil
+}
+
+// InMonth は指定した年・月の公開中の記事を一覧と同じ順 (日付の新しい順、同じ日付はIDの順) で返す
+func (d DateIndex) InMonth(ctx context.Context, year, month int, now time.Time) ([]Summary, error) {
+	summaries := []Summary{}
+	err := d.query(ctx, summaryAttributes, "begins_with(#listingDate, :month)", map[string]types.AttributeValue{
+		":month": &types.AttributeValueMemberS{Value: fmt.Sprintf("%04d-%02d", year, month)},
+	}, false, now, func(s Summary) bool {
+		summaries = append(summaries, s)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].ListingDate != summaries[j].ListingDate {
+			return summaries[i].ListingDate > summaries[j].ListingDate
+		}
+		return summaries[i].ID < summaries[j].ID
+	})
+	return summaries, nil
+}
+
+// query は日付のインデックスの公開中の記事を日付の順 (forward の場合は古い順) に読み、visit が false を返すまで渡す
+// attributes は読み出す属性、condition はソートキー (#listingDate) の条件 (空の場合はすべて)
+func (d DateIndex) query(ctx context.Context, attributes []string, condition string, values map[string]types.AttributeValue, forward bool, now time.Time, visit func(Summary) bool) error {
+	keyCondition := "#listing = :listing"
+	if condition != "" {
+		keyCondition += " AND " + condition
+	}
+	expression, names := projection.Expression(attributes...)
+	names["#listing"] = "listing"
+	names["#listingDate"] = "listingDate"
+	if values == nil {
+		values = map[string]types.AttributeValue{}
+	}
+	values[":listing"] = &types.AttributeValueMemberS{Value: ListingPublished}
+
+	paginator := dynamodb.NewQueryPaginator(d.Client, &dynamodb.QueryInput{
+		TableName:                 aws.String(d.TableName),
+		IndexName:                 aws.String(d.IndexName),
+		KeyConditionExpression:    aws.String(keyCondition),
+		ProjectionExpression:      expression,
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(forward),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to query %s: %w", d.IndexName, err)
+		}
+		var summaries []Summary
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &summaries); err != nil {
+			return fmt.Errorf("failed to unmarshal posts: %w", err)
+		}
+		for _, s := range summaries {
+			if Expired(s.ExpireAt, now) {
+				continue
+			}
+			if !visit(s) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// yearMonth は日付 (YYYY-MM-DD) から年と月を取り出す
+func yearMonth(date string) (year, month int, ok bool) {
+	if len(date) < 7 || date[4] != '-' {
+		return 0, 0, false
+	}
+	year, err := strconv.Atoi(date[:4])
+	if err != nil {
+		return 0, 0, false
+	}
+	month, err = strconv.Atoi(date[5:7])
+	if err != nil || month < 1 || month > 12 {
+		return 0, 0, false
+	}
+	return year, month, true
+}
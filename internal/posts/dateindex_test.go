@@ -0,0 +1,76 @@
+package posts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListingDate(t *testing.T) {
+	// 表示用のタイムゾーンは既定値 (Asia/Tokyo)
+	tests := []struct {
+		name        string
+		date        string
+		publishedAt string
+		want        string
+	}{
+		{"date only", "2024-05-01", "2024-06-01T00:00:00Z", "2024-05-01"},
+		{"surrounding spaces", " 2024-05-01 ", "", "2024-05-01"},
+		{"slashes", "2024/05/01", "", "2024-05-01"},
+		{"dots", "2024.05.01", "", "2024-05-01"},
+		{"no zero padding", "2024-5-1", "", "2024-05-01"},
+		{"no zero padding with slashes", "2024/5/1", "", "2024-05-01"},
+		{"japanese", "2024年5月1日", "", "2024-05-01"},
+		{"rfc3339 in site timezone", "2024-04-30T20:00:00Z", "", "2024-05-01"},
+		{"free-form falls back to publishedAt", "2024年春", "2024-04-30T20:00:00Z", "2024-05-01"},
+		{"invalid day falls back to publishedAt", "2024-02-30", "2024-03-01T00:00:00Z", "2024-03-01"},
+		{"empty falls back to publishedAt", "", "2024-03-01T00:00:00Z", "2024-03-01"},
+		{"unknown", "someday", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ListingDate(tt.date, tt.publishedAt); got != tt.want {
+				t.Errorf("ListingDate(%q, %q) = %q, want %q", tt.date, tt.publishedAt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYearMonth(t *testing.T) {
+	tests := []struct {
+		date        string
+		year, month int
+		ok          bool
+	}{
+		{"2024-05-01", 2024, 5, true},
+		{"2024-12", 2024, 12, true},
+		{"2024-13-01", 0, 0, false},
+		{"2024/05/01", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		year, month, ok := yearMonth(tt.date)
+		if year != tt.year || month != tt.month || ok != tt.ok {
+			t.Errorf("yearMonth(%q) = %d, %d, %v, want %d, %d, %v", tt.date, year, month, ok, tt.year, tt.month, tt.ok)
+		}
+	}
+}
+
+func TestPublishedOrder(t *testing.T) {
+	items := []Item{
+		{ID: "jan-5", Date: "2024/1/5", IsPublished: true},
+		{ID: "jan-10", Date: "2024-01-10", IsPublished: true},
+		{ID: "spring", Date: "2024年春", PublishedAt: "2024-03-01T00:00:00Z", IsPublished: true},
+		{ID: "b-same-day", Date: "2024年1月10日", IsPublished: true},
+		{ID: "draft", Date: "2025-01-01", IsPublished: false},
+		{ID: "archived", Date: "2025-01-01", IsPublished: true, Archived: true},
+		{ID: "expired", Date: "2025-01-01", IsPublished: true, ExpireAt: "2000-01-01T00:00:00Z"},
+	}
+	var got []string
+	for _, item := range Published(items) {
+		got = append(got, item.ID)
+	}
+	want := []string{"spring", "b-same-day", "jan-10", "jan-5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Published = %v, want %v", got, want)
+	}
+}
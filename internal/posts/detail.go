@@ -0,0 +1,45 @@
+package posts
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
+)
+
+// Detail: 記事の詳細のレスポンス (GET /posts/{id} と GET /posts/by-slug/{slug} で同じ形で返す)
+// 投稿テーブルの記事に、一覧で前後にある記事を加えたもの
+type Detail struct {
+	Item
+	Prev *Summary `json:"prev" dynamodbav:"-"` // 日付が1つ古い公開中の記事 (日付のインデックスから読む)
+	Next *Summary `json:"next" dynamodbav:"-"` // 日付が1つ新しい公開中の記事
+}
+
+// NewDetail は記事の詳細を作る
+// 関連記事が無い場合も related は空の配列にし、公開中の記事には前後の記事を加える (公開を取り消した記事には付けない)
+func NewDetail(ctx context.Context, dateIndex DateIndex, item Item, now time.Time) (Detail, error) {
+	detail := Detail{Item: item}
+	if detail.Related == nil {
+		detail.Related = []Related{}
+	}
+	if item.IsPublished {
+		// listingDate を付ける前に公開した記事 (backfill-date-index を実行する前) は date から計算する
+		date := cmp.Or(item.ListingDate, ListingDate(item.Date, item.PublishedAt))
+		var err error
+		detail.Prev, detail.Next, err = dateIndex.Neighbors(ctx, item.ID, date, now)
+		if err != nil {
+			return Detail{}, err
+		}
+	}
+	return detail, nil
+}
+
+// ETag は記事の version に関連記事と前後の記事のハッシュを加えた ETag を返す
+// どちらもほかの記事の公開で version を進めずに変わるため、ハッシュで変更を検知できるようにする
+// If-Match には "-" 以降を無視してそのまま使える
+func (d Detail) ETag() string {
+	derived, _ := json.Marshal([]any{d.Related, d.Prev, d.Next})
+	return version.ETagWith(d.Version, derived)
+}
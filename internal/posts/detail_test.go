@@ -0,0 +1,48 @@
+package posts
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewDetailUnpublished(t *testing.T) {
+	// 公開を取り消した記事は日付のインデックスを読まない (DateIndex が空でもよい)
+	detail, err := NewDetail(context.Background(), DateIndex{}, Item{ID: "a", Version: 3}, time.Now())
+	if err != nil {
+		t.Fatalf("NewDetail: %v", err)
+	}
+	if detail.Related == nil || len(detail.Related) != 0 {
+		t.Errorf("Related = %#v, want empty slice", detail.Related)
+	}
+	if detail.Prev != nil || detail.Next != nil {
+		t.Errorf("Prev, Next = %v, %v, want nil", detail.Prev, detail.Next)
+	}
+}
+
+func TestDetailETag(t *testing.T) {
+	base := Detail{Item: Item{ID: "a", Version: 3, Related: []Related{}}}
+	withRelated := base
+	withRelated.Related = []Related{{ID: "b", Title: "B"}}
+	withPrev := base
+	withPrev.Prev = &Summary{ID: "c"}
+	bumped := base
+	bumped.Version = 4
+
+	etags := map[string]string{}
+	for name, d := range map[string]Detail{"base": base, "related": withRelated, "prev": withPrev, "bumped": bumped} {
+		etag := d.ETag()
+		for other, e := range etags {
+			if e == etag {
+				t.Errorf("ETag of %s equals ETag of %s: %s", name, other, etag)
+			}
+		}
+		etags[name] = etag
+	}
+	if base.ETag() != base.ETag() {
+		t.Error("ETag is not stable")
+	}
+	if got := etags["related"][:3]; got != `"3-` {
+		t.Errorf("ETag = %s, want version 3 prefix", etags["related"])
+	}
+}
@@ -0,0 +1,170 @@
+// Package posts は投稿テーブル (blog_posts) の読み出しを扱う
+package posts
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/markdown"
+	"github.com/sunshine-724/my-homepage-backend/internal/timestamp"
+)
+
+// Item: DynamoDBの投稿テーブルに保存されているデータ構造
+type Item struct {
+	ID                 string   `json:"id" dynamodbav:"id"`
+	Title              string   `json:"title" dynamodbav:"title"`
+	Slug               string   `json:"slug,omitempty" dynamodbav:"slug,omitempty"`
+	Date               string   `json:"date" dynamodbav:"date"`
+	ListingDate        string   `json:"-" dynamodbav:"listingDate,omitempty"` // 日付のインデックスのソートキー (公開中の記事だけ、ListingDate で計算する)
+	Content            string   `json:"content" dynamodbav:"content"`
+	ContentHTML        string   `json:"contentHtml,omitempty" dynamodbav:"contentHtml,omitempty"`
+	Tags               []string `json:"tags" dynamodbav:"tags"`
+	AttachmentFilePath []string `json:"attachmentFilePath,omitempty" dynamodbav:"attachmentFilePath"`
+	IsPublished        bool     `json:"isPublished" dynamodbav:"isPublished"`
+	NoIndex            bool     `json:"noIndex,omitempty" dynamodbav:"noindex,omitempty"`   // 検索エンジンにインデックスさせない
+	Archived           bool     `json:"archived,omitempty" dynamodbav:"archived,omitempty"` // アーカイブ済み (一覧・フィード・サイトマップから除外する)
+	ArchivedAt         string   `json:"archivedAt,omitempty" dynamodbav:"archivedAt,omitempty"`
+	ExpireAt           string   `json:"expireAt,omitempty" dynamodbav:"expireAt,omitempty"`       // 公開を終了する日時 (UTCのRFC 3339)
+	TTL                int64    `json:"ttl" dynamodbav:"ttl,omitempty"`                           // 下書きテーブルでのみ使う (投稿テーブルでは常に0)
+	CreatedAt          string   `json:"createdAt,omitempty" dynamodbav:"createdAt,omitempty"`     // 下書きを作成した日時 (UTCのRFC 3339)
+	UpdatedAt          string   `json:"updatedAt,omitempty" dynamodbav:"updatedAt,omitempty"`     // 最後に更新した日時 (UTCのRFC 3339)
+	PublishedAt        string   `json:"publishedAt,omitempty" dynamodbav:"publishedAt,omitempty"` // 最初に公開した日時 (UTCのRFC 3339)
+	Version            int      `json:"version" dynamodbav:"version"`                             // 楽観的排他制御のversion
+	CharCount          int      `json:"charCount,omitempty" dynamodbav:"charCount,omitempty"`     // 本文のCJKの文字数 (公開時に計算する)
+	WordCount          int      `json:"wordCount,omitempty" dynamodbav:"wordCount,omitempty"`     // 本文のCJK以外の単語数 (公開時に計算する)
+	ReadingTime        int      `json:"readingTime,omitempty" dynamodbav:"readingTime,omitempty"` // 読了時間の目安 (分、公開時に計算する)
+	Excerpt            string   `json:"excerpt,omitempty" dynamodbav:"excerpt,omitempty"`         // プレーンテキストの要約 (公開時に計算する)
+
+	// TOC は見出しの目次 (公開時に本文から作る。アンカーIDは contentHtml の見出しと同じ)
+	TOC []markdown.Heading `json:"toc,omitempty" dynamodbav:"toc,omitempty"`
+	// Related は関連記事 (記事の公開・アーカイブのたびに計算し直す)。レスポンスでは関連記事が無い場合も空の配列で返す
+	Related []Related `json:"related" dynamodbav:"related,omitempty"`
+}
+
+// Related: 関連記事
+type Related struct {
+	ID    string `json:"id" dynamodbav:"id"`
+	Title string `json:"title" dynamodbav:"title"`
+	Slug  string `json:"slug,omitempty" dynamodbav:"slug,omitempty"`
+}
+
+// Expired は記事の公開終了日時を過ぎているかを返す
+// スケジューラーがアーカイブするまでの間も、日時を過ぎた記事は配信しない
+func (item Item) Expired(now time.Time) bool {
+	return Expired(item.ExpireAt, now)
+}
+
+// Expired は公開終了日時 (RFC 3339) がnow以前かを返す (空の場合は常にfalse)
+func Expired(expireAt string, now time.Time) bool {
+	if expireAt == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, expireAt)
+	if err != nil {
+		return false
+	}
+	return !t.After(now)
+}
+
+// HasTag は記事が指定されたタグを持つかを返す
+func (item Item) HasTag(tag string) bool {
+	for _, t := range item.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Get は記事を1件取得する (存在しない場合は nil)
+func Get(ctx context.Context, client *dynamodb.Client, tableName, id string) (*Item, error) {
+	result, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var item Item
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ScanAll は投稿テーブルの全アイテムを取得する (ページングを最後まで辿る)
+func ScanAll(ctx context.Context, client *dynamodb.Client, tableName string) ([]Item, error) {
+	var items []Item
+
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName: aws.String(tableName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var pageItems []Item
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageItems); err != nil {
+			return nil, err
+		}
+		items = append(items, pageItems...)
+	}
+
+	return items, nil
+}
+
+// Published は公開中の記事 (公開済みで、アーカイブ・公開終了されていないもの) だけを日付の新しい順に並べて返す
+// 日付は自由形式のため、日付のインデックスと同じく ListingDate で揃えた日付で並べる (同じ日付はIDの順)
+func Published(items []Item) []Item {
+	now := time.Now()
+	var published []Item
+	dates := map[string]string{}
+	for _, item := range items {
+		if item.IsPublished && !item.Archived && !item.Expired(now) {
+			published = append(published, item)
+			dates[item.ID] = ListingDate(item.Date, item.PublishedAt)
+		}
+	}
+	sort.SliceStable(published, func(i, j int) bool {
+		if di, dj := dates[published[i].ID], dates[published[j].ID]; di != dj {
+			return di > dj
+		}
+		return published[i].ID < published[j].ID
+	})
+	return published
+}
+
+// Archive は記事をアーカイブする (一覧・フィード・サイトマップから除外される)
+// 記事の更新なので version も進める (編集中のクライアントは If-Match で変更に気づける)
+// 日付のインデックスからも取り除く (listing・listingDate を消す)
+func Archive(ctx context.Context, client *dynamodb.Client, tableName, id string, now time.Time) error {
+	_, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		UpdateExpression:         aws.String("SET archived = :true, archivedAt = :now, updatedAt = :now, #version = if_not_exists(#version, :zero) + :one REMOVE listing, listingDate"),
+		ConditionExpression:      aws.String("attribute_exists(id)"),
+		ExpressionAttributeNames: map[string]string{"#version": "version"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":true": &types.AttributeValueMemberBOOL{Value: true},
+			":now":  &types.AttributeValueMemberS{Value: timestamp.Format(now)},
+			":zero": &types.AttributeValueMemberN{Value: "0"},
+			":one":  &types.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	return err
+}
@@ -0,0 +1,77 @@
+// Package projection は一覧のエンドポイントの ?fields= (返すフィールドの指定) を扱う
+//
+// 指定されたフィールドは DynamoDB の ProjectionExpression に変換し、
+// 読み出す属性そのものを減らす (レスポンスから取り除くだけにはしない)。
+package projection
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// Fields: 返すフィールドの集合 (JSONのキー)
+type Fields []string
+
+// Parse は ?fields= の値 (カンマ区切り) を読む
+// allowed に含まれないフィールドはエラーにする (空の場合は nil を返す)
+func Parse(value string, allowed []string) (Fields, error) {
+	var fields Fields
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || slices.Contains(fields, field) {
+			continue
+		}
+		if !slices.Contains(allowed, field) {
+			return nil, fmt.Errorf("unknown field %q (allowed: %s)", field, strings.Join(allowed, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Has はフィールドが含まれるかを返す
+func (f Fields) Has(field string) bool {
+	return slices.Contains(f, field)
+}
+
+// Expression は属性名から ProjectionExpression と ExpressionAttributeNames を作る
+// 予約語 (date, content, ttl など) と衝突しないよう、すべての属性名をプレースホルダーにする
+func Expression(attributes ...string) (*string, map[string]string) {
+	names := map[string]string{}
+	var placeholders []string
+	for _, attribute := range attributes {
+		placeholder := "#" + attribute
+		if _, ok := names[placeholder]; ok {
+			continue
+		}
+		names[placeholder] = attribute
+		placeholders = append(placeholders, placeholder)
+	}
+	sort.Strings(placeholders)
+	return aws.String(strings.Join(placeholders, ", ")), names
+}
+
+// Select は v をJSONにしたときのキーのうち、fields に含まれるものだけを残したマップを返す
+func Select(v any, fields Fields) (map[string]any, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]any
+	if err := json.Unmarshal(body, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]any, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,73 @@
+package projection
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	allowed := []string{"id", "title", "date", "tags"}
+	tests := []struct {
+		name    string
+		value   string
+		want    Fields
+		wantErr string
+	}{
+		{"single", "id", Fields{"id"}, ""},
+		{"keeps order", "title,id", Fields{"title", "id"}, ""},
+		{"spaces", " id , title ", Fields{"id", "title"}, ""},
+		{"duplicates", "id,title,id", Fields{"id", "title"}, ""},
+		{"empty entries", ",id,,title,", Fields{"id", "title"}, ""},
+		{"empty", "", nil, ""},
+		{"only commas", ",,", nil, ""},
+		{"unknown", "id,content", nil, `unknown field "content"`},
+		{"case-sensitive", "ID", nil, `unknown field "ID"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.value, allowed)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpression(t *testing.T) {
+	expression, names := Expression("title", "date", "id", "date")
+	if *expression != "#date, #id, #title" {
+		t.Errorf("Expression = %s", *expression)
+	}
+	want := map[string]string{"#date": "date", "#id": "id", "#title": "title"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	type item struct {
+		ID    string   `json:"id"`
+		Title string   `json:"title"`
+		Tags  []string `json:"tags"`
+		Slug  string   `json:"slug,omitempty"`
+	}
+	got, err := Select(item{ID: "a", Title: "A", Tags: []string{"go"}}, Fields{"id", "tags", "slug"})
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	// omitempty で省略されたキーは含めない
+	want := map[string]any{"id": "a", "tags": []any{"go"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Select = %v, want %v", got, want)
+	}
+	if !(Fields{"id", "tags"}).Has("tags") || (Fields{"id"}).Has("tags") {
+		t.Error("Has is wrong")
+	}
+}
@@ -0,0 +1,53 @@
+// Package publish は記事の公開・再公開に共通する処理を扱う
+package publish
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/attachments"
+	"github.com/sunshine-724/my-homepage-backend/internal/markdown"
+)
+
+// AttachmentBaseURL は添付ファイルの公開URLのベース (ATTACHMENT_BASE_URL) を確かめて返す
+// 添付ファイルのバケットは非公開 (get-draft は署名付きURLを返す) のため、S3のURLでは代用できない
+// 未設定の場合はエラーをログに出す (Render は相対参照を置き換えずに警告を返す)
+func AttachmentBaseURL(configured string) string {
+	if configured == "" {
+		fmt.Fprintln(os.Stderr, "Error: ATTACHMENT_BASE_URL is not set; relative attachment references will not be resolved")
+	}
+	return configured
+}
+
+// Render は本文をHTMLにレンダリングする (添付ファイルへの相対参照は公開URLに置き換える)
+// attachmentBaseURL が空の場合は相対参照をそのまま残し、警告を加える
+func Render(content string, keys []string, attachmentBaseURL string) (markdown.Result, error) {
+	var files []markdown.Attachment
+	if attachmentBaseURL != "" {
+		files = Attachments(attachmentBaseURL, keys)
+	}
+	result, err := markdown.Render(content, markdown.Options{Attachments: files})
+	if err == nil && attachmentBaseURL == "" && len(keys) > 0 {
+		result.Warnings = append([]string{"ATTACHMENT_BASE_URL is not set; attachment references were left unresolved"}, result.Warnings...)
+	}
+	return result, err
+}
+
+// Attachments は添付ファイルのキーからレンダリング用の公開URLの一覧を作る
+func Attachments(baseURL string, keys []string) []markdown.Attachment {
+	var result []markdown.Attachment
+	for _, file := range attachments.Group(keys) {
+		attachment := markdown.Attachment{
+			Name: file.Name,
+			URL:  attachments.PublicURL(baseURL, file.Key),
+		}
+		for _, v := range file.Variants {
+			attachment.Variants = append(attachment.Variants, markdown.Variant{
+				URL:   attachments.PublicURL(baseURL, v.Key),
+				Width: v.Width,
+			})
+		}
+		result = append(result, attachment)
+	}
+	return result
+}
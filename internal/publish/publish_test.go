@@ -0,0 +1,31 @@
+package publish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderAttachmentBaseURL(t *testing.T) {
+	content := "![](example.png)"
+	keys := []string{"draft-1/example.png"}
+
+	rendered, err := Render(content, keys, "https://cdn.example.com")
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(rendered.HTML, `src="https://cdn.example.com/draft-1/example.png"`) || len(rendered.Warnings) != 0 {
+		t.Errorf("Render = %s, warnings %v", rendered.HTML, rendered.Warnings)
+	}
+
+	// ATTACHMENT_BASE_URL が未設定の場合は、取得できないURLを作らずに相対参照を残す
+	rendered, err = Render(content, keys, "")
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if strings.Contains(rendered.HTML, "amazonaws.com") || !strings.Contains(rendered.HTML, `src="example.png"`) {
+		t.Errorf("Render without base URL = %s", rendered.HTML)
+	}
+	if len(rendered.Warnings) == 0 || !strings.Contains(rendered.Warnings[0], "ATTACHMENT_BASE_URL") {
+		t.Errorf("Warnings = %v, want ATTACHMENT_BASE_URL warning first", rendered.Warnings)
+	}
+}
@@ -0,0 +1,366 @@
+package publish
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/clock"
+	"github.com/sunshine-724/my-homepage-backend/internal/markdown"
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+	"github.com/sunshine-724/my-homepage-backend/internal/revision"
+	"github.com/sunshine-724/my-homepage-backend/internal/search"
+	"github.com/sunshine-724/my-homepage-backend/internal/slug"
+	"github.com/sunshine-724/my-homepage-backend/internal/textstat"
+	"github.com/sunshine-724/my-homepage-backend/internal/timestamp"
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
+)
+
+var (
+	// ErrDraftNotFound は公開しようとした下書きが存在しない場合のエラー
+	ErrDraftNotFound = errors.New("draft not found")
+	// ErrPostModified は公開の途中で公開済みの記事が他のリクエストによって更新された場合のエラー
+	ErrPostModified = errors.New("post was modified by another request")
+)
+
+// Item: DynamoDBの下書きテーブルと公開用テーブルで共有するデータ構造
+type Item struct {
+	ID                 string   `dynamodbav:"id"`
+	Title              string   `dynamodbav:"title"`
+	Slug               string   `dynamodbav:"slug,omitempty"` // 公開URL用のスラッグ (公開時に割り当てる)
+	Date               string   `dynamodbav:"date"`
+	Content            string   `dynamodbav:"content"`
+	ContentHTML        string   `dynamodbav:"contentHtml,omitempty"` // contentをサーバーサイドでレンダリングしたHTML
+	Tags               []string `dynamodbav:"tags"`
+	AttachmentFilePath []string `dynamodbav:"attachmentFilePath"` // S3に保存したファイルのパス
+	IsPublished        bool     `dynamodbav:"isPublished"`
+	NoIndex            bool     `dynamodbav:"noindex,omitempty"`
+	PublishAt          string   `dynamodbav:"publishAt,omitempty"`   // 予約公開の日時 (下書きテーブルでのみ有効)
+	ExpireAt           string   `dynamodbav:"expireAt,omitempty"`    // 公開終了の日時 (公開後も引き継ぐ)
+	TTL                int64    `dynamodbav:"ttl"`                   // 下書きテーブルでのみ有効
+	CreatedAt          string   `dynamodbav:"createdAt,omitempty"`   // 下書きを作成した日時 (UTCのRFC 3339、公開後も引き継ぐ)
+	UpdatedAt          string   `dynamodbav:"updatedAt,omitempty"`   // 最後に更新した日時 (UTCのRFC 3339)
+	PublishedAt        string   `dynamodbav:"publishedAt,omitempty"` // 最初に公開した日時 (UTCのRFC 3339、投稿テーブルでのみ有効)
+	Version            int      `dynamodbav:"version"`               // 楽観的排他制御のversion (テーブルごとに数える)
+	CharCount          int      `dynamodbav:"charCount,omitempty"`   // 本文のCJKの文字数 (公開時に計算する)
+	WordCount          int      `dynamodbav:"wordCount,omitempty"`   // 本文のCJK以外の単語数 (公開時に計算する)
+	ReadingTime        int      `dynamodbav:"readingTime,omitempty"` // 読了時間の目安 (分、公開時に計算する)
+	Excerpt            string   `dynamodbav:"excerpt,omitempty"`     // プレーンテキストの要約 (公開時に計算する)
+
+	// TOC は見出しの目次 (公開時に本文から作る)
+	TOC []markdown.Heading `dynamodbav:"toc,omitempty"`
+	// Related は関連記事 (公開のたびに UpdateRelated で計算し直す。公開時の Put では引き継ぐ)
+	Related []posts.Related `dynamodbav:"related,omitempty"`
+	// Listing は日付のインデックスのパーティションキー (公開中の記事だけ posts.ListingPublished、それ以外は付けない)
+	Listing string `dynamodbav:"listing,omitempty"`
+	// ListingDate は日付のインデックスのソートキー (公開中の記事だけ posts.ListingDate で計算する)
+	ListingDate string `dynamodbav:"listingDate,omitempty"`
+}
+
+// Request: 公開の指示
+type Request struct {
+	ID          string // 下書きのID
+	IsPublished bool
+	Slug        string // 省略した場合は公開済みのスラッグを引き継ぐか、タイトルから生成する
+	Author      string // リビジョンに記録する公開した人
+	// DraftVersion は If-Match で指定された下書きのversion (version.Any の場合は確認しない)
+	DraftVersion int
+}
+
+// Result: 公開の結果
+type Result struct {
+	ID          string
+	Slug        string
+	Revision    int
+	Version     int      // 公開した記事のversion
+	PublishedAt string   // 最初に公開した日時
+	Warnings    []string // 本文中の相対参照のうち、どの添付ファイルにも一致しなかったものなど
+}
+
+// Publisher は下書きを投稿テーブルへ移して公開する
+// POST /posts (publish-post) と予約公開のスケジューラーで同じ処理を使う
+type Publisher struct {
+	Client            *dynamodb.Client
+	DraftsTable       string
+	PostsTable        string
+	SlugsTable        string
+	RevisionsTable    string
+	AttachmentBaseURL string
+	Clock             clock.Clock   // updatedAt / publishedAt に使う時計 (nil の場合は clock.System)
+	Search            *search.Store // 全文検索のインデックス (nil の場合は更新しない)
+}
+
+// Publish は下書きを公開する
+// スラッグのエラーは slug.ErrInvalid / slug.ErrTaken、下書きのversionの不一致は *version.MismatchError をラップして返す
+func (p *Publisher) Publish(ctx context.Context, req Request) (Result, error) {
+	// 1. blog_drafts テーブルから下書きデータを取得
+	fmt.Printf("Getting item from drafts table: %s\n", req.ID)
+	result, err := p.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(p.DraftsTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: req.ID},
+		},
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get draft: %w", err)
+	}
+	if result.Item == nil {
+		return Result{}, ErrDraftNotFound
+	}
+
+	var item Item
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return Result{}, fmt.Errorf("failed to unmarshal draft: %w", err)
+	}
+	draftVersion := item.Version
+	if err := version.Check(req.DraftVersion, draftVersion); err != nil {
+		return Result{}, fmt.Errorf("failed to check draft version: %w", err)
+	}
+
+	// 2. 公開フラグを更新
+	item.IsPublished = req.IsPublished
+	// blog_postsテーブルにTTLと予約公開の日時は不要
+	item.TTL = 0
+	item.PublishAt = ""
+
+	// 3. 本文をHTMLにレンダリング (添付ファイルへの相対参照は公開URLに置き換える)
+	// あわせて一覧・詳細で返す目次・文字数・読了時間・要約を作る
+	rendered, err := Render(item.Content, item.AttachmentFilePath, p.AttachmentBaseURL)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to render content: %w", err)
+	}
+	item.ContentHTML = rendered.HTML
+	item.TOC = rendered.TOC
+	for _, warning := range rendered.Warnings {
+		fmt.Printf("Render warning for %s: %s\n", item.ID, warning)
+	}
+	stats := textstat.Analyze(item.Content)
+	item.CharCount, item.WordCount, item.ReadingTime, item.Excerpt = stats.CharCount, stats.WordCount, stats.ReadingTime, stats.Excerpt
+
+	// 4. スラッグを決める (スラッグのインデックスへの書き込みは 5. の記事の保存と同じトランザクションで行う)
+	// 再公開の場合は公開済みの記事のスラッグとversion、関連記事を引き継ぐ
+	existing, err := p.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(p.PostsTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: item.ID},
+		},
+		ProjectionExpression:     aws.String("slug, #version, createdAt, publishedAt, related"),
+		ExpressionAttributeNames: map[string]string{"#version": "version"},
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get post: %w", err)
+	}
+	var current struct {
+		Slug        string          `dynamodbav:"slug"`
+		Version     int             `dynamodbav:"version"`
+		CreatedAt   string          `dynamodbav:"createdAt"`
+		PublishedAt string          `dynamodbav:"publishedAt"`
+		Related     []posts.Related `dynamodbav:"related"`
+	}
+	if err := attributevalue.UnmarshalMap(existing.Item, &current); err != nil {
+		return Result{}, fmt.Errorf("failed to unmarshal post: %w", err)
+	}
+
+	now := p.now()
+	var slugItems []types.TransactWriteItem
+	item.Slug, slugItems, err = p.assignSlug(ctx, req.Slug, current.Slug, item, now)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to assign slug: %w", err)
+	}
+
+	// サーバーが管理する日時を設定する (再公開の場合、作成・最初の公開の日時は公開済みの記事から引き継ぐ)
+	item.UpdatedAt = timestamp.Format(now)
+	item.PublishedAt = cmp.Or(current.PublishedAt, item.UpdatedAt)
+	item.CreatedAt = cmp.Or(current.CreatedAt, item.CreatedAt, item.UpdatedAt)
+	item.Date = timestamp.Date(item.Date, now)
+	item.Related = current.Related
+	if item.IsPublished {
+		item.Listing = posts.ListingPublished
+		item.ListingDate = posts.ListingDate(item.Date, item.PublishedAt)
+	}
+
+	// 5. blog_posts テーブルにデータを保存
+	// 読み出してから他のリクエストで記事が作成・更新されていた場合は上書きしない
+	put := &types.Put{TableName: aws.String(p.PostsTable)}
+	if existing.Item == nil {
+		put.ConditionExpression = aws.String("attribute_not_exists(id)")
+	} else {
+		condition, names, values := version.Condition(current.Version)
+		put.ConditionExpression = aws.String(condition)
+		put.ExpressionAttributeNames = names
+		put.ExpressionAttributeValues = values
+	}
+	item.Version = current.Version + 1
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to marshal post: %w", err)
+	}
+
+	// 記事の保存と公開時点のリビジョンの記録、スラッグの割り当てを1つのトランザクションで行う
+	rev, err := revision.Next(ctx, p.Client, p.RevisionsTable, item.ID)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get revisions: %w", err)
+	}
+	revisionPut, err := revision.Put(p.RevisionsTable, revision.New(
+		item.ID, rev, item.Title, item.Date, item.Content, item.Tags, req.Author, revision.SourcePublish,
+	))
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to marshal revision: %w", err)
+	}
+
+	put.Item = av
+	transactItems := []types.TransactWriteItem{{Put: put}, revisionPut}
+	// 記事の保存に失敗した場合にスラッグだけが変わらないよう、スラッグのインデックスへの書き込みも含める
+	slugIndex := len(transactItems)
+	transactItems = append(transactItems, slugItems...)
+	draftIndex := len(transactItems)
+	// If-Match で version を指定した場合は、読み出してから下書きが更新・削除されていないことも同じトランザクションで確認する
+	if req.DraftVersion != version.Any {
+		condition, names, values := version.Condition(req.DraftVersion)
+		transactItems = append(transactItems, types.TransactWriteItem{
+			ConditionCheck: &types.ConditionCheck{
+				TableName: aws.String(p.DraftsTable),
+				Key: map[string]types.AttributeValue{
+					"id": &types.AttributeValueMemberS{Value: req.ID},
+				},
+				ConditionExpression:       aws.String("attribute_exists(id) AND " + condition),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			},
+		})
+	}
+	fmt.Printf("Putting item to posts table: %s (revision %d, version %d)\n", item.ID, rev, item.Version)
+	_, err = p.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	if req.DraftVersion != version.Any && version.ConditionFailed(err, draftIndex) {
+		mismatch, conflictErr := version.Conflict(ctx, p.Client, p.DraftsTable, req.ID)
+		if conflictErr != nil {
+			return Result{}, fmt.Errorf("failed to get draft version: %w", conflictErr)
+		}
+		if mismatch == nil {
+			return Result{}, ErrDraftNotFound
+		}
+		return Result{}, fmt.Errorf("failed to check draft version: %w", mismatch)
+	}
+	if len(slugItems) > 0 && version.ConditionFailed(err, slugIndex) {
+		// 決めてから保存するまでの間に、他の記事がスラッグを使った
+		return Result{}, fmt.Errorf("failed to assign slug: %w", slug.ErrTaken)
+	}
+	if version.ConditionFailed(err, 0) || len(slugItems) > 1 && version.ConditionFailed(err, slugIndex+1) {
+		return Result{}, fmt.Errorf("failed to put post to DynamoDB: %w", ErrPostModified)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to put post to DynamoDB: %w", err)
+	}
+	fmt.Printf("Assigned slug %s to %s\n", item.Slug, item.ID)
+	if current.Slug != "" && current.Slug != item.Slug {
+		fmt.Printf("Slug of %s changed from %s to %s\n", item.ID, current.Slug, item.Slug)
+	}
+
+	// 6. blog_drafts テーブルから下書きを削除 (公開した後に更新された下書きは残す)
+	fmt.Printf("Deleting item from drafts table: %s\n", req.ID)
+	condition, names, values := version.Condition(draftVersion)
+	_, err = p.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(p.DraftsTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: req.ID},
+		},
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		// 下書きの削除が失敗しても、投稿自体は成功しているので、ここではエラーを返さない（ログは出す）
+		fmt.Printf("Error deleting item from drafts table: %v\n", err)
+	}
+
+	// 7. 検索インデックスを更新 (公開を取り消した場合はインデックスから取り除く)
+	p.updateSearchIndex(ctx, item)
+
+	// 8. 公開した記事と、その記事に関わる記事の関連記事を計算し直す
+	p.updateRelated(ctx, item.ID)
+
+	return Result{ID: item.ID, Slug: item.Slug, Revision: rev, Version: item.Version, PublishedAt: item.PublishedAt, Warnings: rendered.Warnings}, nil
+}
+
+// updateSearchIndex は公開した記事で検索インデックスを更新する
+// 失敗しても公開自体は成功しているので、エラーは返さない (rebuild-search-index で作り直せる)
+func (p *Publisher) updateSearchIndex(ctx context.Context, item Item) {
+	err := p.Search.Update(ctx, func(idx *search.Index) {
+		if !item.IsPublished {
+			idx.Remove(item.ID)
+			return
+		}
+		idx.Put(search.Document{
+			ID:       item.ID,
+			Title:    item.Title,
+			Slug:     item.Slug,
+			Date:     item.Date,
+			Tags:     item.Tags,
+			Content:  item.Content,
+			ExpireAt: item.ExpireAt,
+		})
+	})
+	if err != nil {
+		fmt.Printf("Error updating search index for %s: %v\n", item.ID, err)
+	}
+}
+
+func (p *Publisher) now() time.Time {
+	if p.Clock == nil {
+		return clock.System.Now()
+	}
+	return p.Clock.Now()
+}
+
+// assignSlug は記事に割り当てるスラッグを決めて、スラッグのインデックスへの書き込みを返す
+// 指定されたスラッグ > 公開済みの記事のスラッグ > タイトルから生成したスラッグ の順に使う
+// スラッグが変わった場合、以前のスラッグは新しいスラッグへのリダイレクトとして残す
+// current は公開済みの記事のスラッグ (初めて公開する場合は空)
+func (p *Publisher) assignSlug(ctx context.Context, requested, current string, item Item, now time.Time) (string, []types.TransactWriteItem, error) {
+	if requested == "" && current != "" {
+		return current, nil, nil
+	}
+
+	var assigned string
+	if requested == "" {
+		generated, err := slug.Unique(ctx, p.Client, p.SlugsTable, slug.Generate(item.Title, item.ID), item.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		assigned = generated
+	} else {
+		normalized, err := slug.Normalize(requested)
+		if err != nil {
+			return "", nil, err
+		}
+		assigned = normalized
+	}
+
+	put, err := slug.Put(p.SlugsTable, assigned, item.ID, now)
+	if err != nil {
+		return "", nil, err
+	}
+	items := []types.TransactWriteItem{put}
+	if current == "" || current == assigned {
+		return assigned, items, nil
+	}
+
+	// 以前のスラッグが既に他の用途に使われている場合は、以前のスラッグには何もしない
+	owned, err := slug.Owned(ctx, p.Client, p.SlugsTable, current, item.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	if owned {
+		items = append(items, slug.Retire(p.SlugsTable, current, item.ID, now))
+	}
+	return assigned, items, nil
+}
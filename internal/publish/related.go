@@ -0,0 +1,176 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+	"github.com/sunshine-724/my-homepage-backend/internal/projection"
+	"github.com/sunshine-724/my-homepage-backend/internal/search"
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
+)
+
+// RelatedCount: 記事ごとに保存する関連記事の数
+const RelatedCount = 5
+
+// RefreshRelated は検索インデックスから全記事の関連記事を計算し直し、変わった記事だけ投稿テーブルを更新する
+// すべての記事の組を比べるため、rebuild-search-index (手動、または EventBridge のスケジュールで定期的に実行する) で使う
+// 記事の公開・アーカイブのたびには、影響する記事だけを UpdateRelated で更新する
+// インデックスに無い記事 (非公開・アーカイブ済み) の関連記事は取り除く。戻り値は更新した記事の数 (store が未設定の場合は何もしない)
+func RefreshRelated(ctx context.Context, client *dynamodb.Client, postsTable string, store *search.Store, now time.Time) (int, error) {
+	if store == nil || store.Bucket == "" {
+		return 0, nil
+	}
+	idx, err := store.Load(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load search index: %w", err)
+	}
+	related := idx.Related(RelatedCount, now)
+
+	updated := 0
+	err = scanRelated(ctx, client, postsTable, func(id string, current []posts.Related) error {
+		if slices.Equal(current, related[id]) {
+			return nil
+		}
+		if err := writeRelated(ctx, client, postsTable, id, related[id]); err != nil {
+			return fmt.Errorf("failed to update related posts of %s: %w", id, err)
+		}
+		updated++
+		return nil
+	})
+	fmt.Printf("Refreshed related posts of %d post(s)\n", updated)
+	return updated, err
+}
+
+// UpdateRelated は公開・公開の取り消し・アーカイブ・復元した記事 (ids) に関わる記事の関連記事だけを計算し直す (store が未設定の場合は何もしない)
+//   - ids の記事: インデックスにある場合は計算し直し、無い場合は関連記事を取り除く
+//   - ids の記事の関連記事 (関連度は対称なので、一覧に加わる可能性が高い)
+//   - ids の記事を関連記事に持つ記事 (保存しているタイトル・スラッグが古くなり、関連度も変わる)
+//
+// 記事ごとの計算はその記事とほかの記事の組だけを比べる (search.Index.RelatedTo)。関連記事が変わらない記事は書き込まない
+// ここで計算し直さない記事の関連記事は、RefreshRelated (rebuild-search-index) で揃える
+func UpdateRelated(ctx context.Context, client *dynamodb.Client, postsTable string, store *search.Store, now time.Time, ids ...string) error {
+	if store == nil || store.Bucket == "" {
+		return nil
+	}
+	idx, err := store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load search index: %w", err)
+	}
+
+	changed := map[string]bool{}
+	targets := map[string]bool{}
+	for _, id := range ids {
+		changed[id] = true
+		targets[id] = true
+		if _, ok := idx.Docs[id]; !ok {
+			continue
+		}
+		for _, r := range idx.RelatedTo(id, RelatedCount, now) {
+			targets[r.ID] = true
+		}
+	}
+
+	// 記事を関連記事に持つ記事は投稿テーブルからしか分からない (関連記事のIDだけを読む)
+	// あわせて今の関連記事を覚えておき、変わらない記事は書き込まない
+	current := map[string][]posts.Related{}
+	err = scanRelated(ctx, client, postsTable, func(id string, related []posts.Related) error {
+		current[id] = related
+		if slices.ContainsFunc(related, func(r posts.Related) bool { return changed[r.ID] }) {
+			targets[id] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	updated := 0
+	for id := range targets {
+		stored, ok := current[id]
+		if !ok {
+			continue // 記事が削除された
+		}
+		related := idx.RelatedTo(id, RelatedCount, now)
+		if slices.Equal(stored, related) {
+			continue
+		}
+		if err := writeRelated(ctx, client, postsTable, id, related); err != nil {
+			if version.ConditionFailed(err, 0) {
+				continue // 記事が削除された
+			}
+			return fmt.Errorf("failed to update related posts of %s: %w", id, err)
+		}
+		updated++
+	}
+	fmt.Printf("Updated related posts of %d of %d affected post(s)\n", updated, len(targets))
+	return nil
+}
+
+// scanRelated は投稿テーブルのすべての記事のIDと関連記事を visit に渡す
+func scanRelated(ctx context.Context, client *dynamodb.Client, postsTable string, visit func(id string, related []posts.Related) error) error {
+	expression, names := projection.Expression("id", "related")
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:                aws.String(postsTable),
+		ProjectionExpression:     expression,
+		ExpressionAttributeNames: names,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to scan posts: %w", err)
+		}
+		var items []struct {
+			ID      string          `dynamodbav:"id"`
+			Related []posts.Related `dynamodbav:"related"`
+		}
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return fmt.Errorf("failed to unmarshal posts: %w", err)
+		}
+		for _, item := range items {
+			if err := visit(item.ID, item.Related); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeRelated は記事の関連記事を書き換える (空の場合は取り除く)
+// 関連記事はほかの記事の公開で変わる派生データなので version は進めない (編集中のクライアントの If-Match を失敗させない)
+// レスポンスの ETag には関連記事のハッシュを含める (version.ETagWith)
+func writeRelated(ctx context.Context, client *dynamodb.Client, postsTable, id string, related []posts.Related) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(postsTable),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		UpdateExpression:    aws.String("REMOVE related"),
+		ConditionExpression: aws.String("attribute_exists(id)"),
+	}
+	if len(related) > 0 {
+		av, err := attributevalue.Marshal(related)
+		if err != nil {
+			return err
+		}
+		input.UpdateExpression = aws.String("SET related = :related")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{":related": av}
+	}
+	_, err := client.UpdateItem(ctx, input)
+	return err
+}
+
+// updateRelated は公開・公開の取り消しの後に関連記事を計算し直す
+// 失敗しても公開自体は成功しているので、エラーは返さない (rebuild-search-index で作り直せる)
+func (p *Publisher) updateRelated(ctx context.Context, id string) {
+	if err := UpdateRelated(ctx, p.Client, p.PostsTable, p.Search, p.now(), id); err != nil {
+		fmt.Printf("Error updating related posts: %v\n", err)
+	}
+}
@@ -0,0 +1,58 @@
+package publish
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScheduleGrace: 予約公開の日時を過ぎてから下書きを残しておく期間
+// スケジューラーの実行が失敗・遅延しても、次の実行で公開できるようにする
+const ScheduleGrace = 24 * time.Hour
+
+// ParsePublishAt は予約公開の日時 (RFC 3339) を検証し、UTCの形式に揃えて返す
+// DynamoDBでは文字列として比較するため、保存する値は必ずこの形式にする。空文字列はそのまま返す
+func ParsePublishAt(s string) (string, error) {
+	return parseTimestamp("publishAt", s)
+}
+
+// ParseExpireAt は公開終了の日時 (RFC 3339) を ParsePublishAt と同じ形式に揃えて返す
+func ParseExpireAt(s string) (string, error) {
+	return parseTimestamp("expireAt", s)
+}
+
+// ValidateSchedule は公開終了の日時が予約公開の日時より後であることを確認する (どちらかが空の場合は確認しない)
+func ValidateSchedule(publishAt, expireAt string) error {
+	if publishAt != "" && expireAt != "" && expireAt <= publishAt {
+		return fmt.Errorf("expireAt %s must be after publishAt %s", expireAt, publishAt)
+	}
+	return nil
+}
+
+func parseTimestamp(field, s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %q: must be RFC 3339", field, s)
+	}
+	return FormatPublishAt(t), nil
+}
+
+// FormatPublishAt は時刻を予約公開の日時の保存形式にする
+func FormatPublishAt(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ScheduledTTL は予約公開する下書きが公開日時より前に期限切れにならないようにTTLを延ばす
+// publishAtが空、または既にTTLが十分先の場合はttlをそのまま返す
+func ScheduledTTL(ttl int64, publishAt string) int64 {
+	if publishAt == "" {
+		return ttl
+	}
+	t, err := time.Parse(time.RFC3339, publishAt)
+	if err != nil {
+		return ttl
+	}
+	return max(ttl, t.Add(ScheduleGrace).Unix())
+}
@@ -0,0 +1,214 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/clock"
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+	"github.com/sunshine-724/my-homepage-backend/internal/search"
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
+)
+
+// SchedulerAuthor: 予約公開で記録するリビジョンの author
+const SchedulerAuthor = "scheduler"
+
+// ScheduledDraft: 予約公開の日時が設定された下書き
+type ScheduledDraft struct {
+	ID        string `dynamodbav:"id"`
+	PublishAt string `dynamodbav:"publishAt"`
+	TTL       int64  `dynamodbav:"ttl"`
+}
+
+// Due は now の時点で公開すべきかを返す
+// 予約公開の日時を過ぎていても、TTLで期限切れになった (DynamoDBがまだ削除していない) 下書きは公開しない
+func (d ScheduledDraft) Due(now time.Time) bool {
+	if d.PublishAt == "" || d.PublishAt > FormatPublishAt(now) {
+		return false
+	}
+	return d.TTL == 0 || d.TTL > now.Unix()
+}
+
+// ExpiringPost: 公開終了の日時が設定された、まだアーカイブされていない記事
+type ExpiringPost struct {
+	ID       string `dynamodbav:"id"`
+	ExpireAt string `dynamodbav:"expireAt"`
+}
+
+// ScheduleStore は Scheduler が読み書きする下書き・記事
+// 本番では DynamoScheduleStore を使い、テストでは差し替える
+type ScheduleStore interface {
+	ScheduledDrafts(ctx context.Context) ([]ScheduledDraft, error)
+	Publish(ctx context.Context, req Request) (Result, error)
+	ExpiringPosts(ctx context.Context) ([]ExpiringPost, error)
+	Archive(ctx context.Context, id string, now time.Time) error
+	// Unlist はアーカイブした記事を検索インデックスなどから取り除く (失敗してもアーカイブは取り消さない)
+	Unlist(ctx context.Context, ids []string, now time.Time)
+}
+
+// Published: 公開した下書き
+type Published struct {
+	ID       string `json:"id"`
+	Slug     string `json:"slug"`
+	Revision int    `json:"revision"`
+}
+
+// Failure: 公開に失敗した下書き、またはアーカイブに失敗した記事
+type Failure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// Report: 実行結果
+type Report struct {
+	Now       string      `json:"now"`
+	Published []Published `json:"published"`
+	Failed    []Failure   `json:"failed"`
+	Archived  []string    `json:"archived"` // アーカイブした記事のID
+}
+
+// Scheduler は予約公開・公開終了の日時に従って下書きを公開し、記事をアーカイブする
+// Clock を差し替えることで任意の時刻での動作を確認できる
+type Scheduler struct {
+	Clock clock.Clock
+	Store ScheduleStore
+}
+
+// Run は実行時点で公開日時を過ぎている下書きを全て公開し、公開終了の日時を過ぎた記事をアーカイブする
+func (s *Scheduler) Run(ctx context.Context) (Report, error) {
+	now := s.Clock.Now()
+	report := Report{Now: FormatPublishAt(now), Published: []Published{}, Failed: []Failure{}, Archived: []string{}}
+
+	scheduled, err := s.Store.ScheduledDrafts(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to find due drafts: %w", err)
+	}
+	var due []string
+	for _, draft := range scheduled {
+		if draft.Due(now) {
+			due = append(due, draft.ID)
+		}
+	}
+	fmt.Printf("Found %d due draft(s)\n", len(due))
+
+	for _, id := range due {
+		result, err := s.Store.Publish(ctx, Request{
+			ID:          id,
+			IsPublished: true,
+			Author:      SchedulerAuthor,
+			// 予約した時点から下書きが更新されていても、公開日時の時点の内容を公開する
+			DraftVersion: version.Any,
+		})
+		if errors.Is(err, ErrDraftNotFound) {
+			// 走査してから公開までの間に手動で公開・削除された
+			fmt.Printf("Draft %s no longer exists, skipping\n", id)
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Error publishing scheduled draft %s: %v\n", id, err)
+			report.Failed = append(report.Failed, Failure{ID: id, Error: err.Error()})
+			continue
+		}
+		report.Published = append(report.Published, Published{ID: result.ID, Slug: result.Slug, Revision: result.Revision})
+	}
+
+	expiring, err := s.Store.ExpiringPosts(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to find expired posts: %w", err)
+	}
+	for _, post := range expiring {
+		if !posts.Expired(post.ExpireAt, now) {
+			continue
+		}
+		if err := s.Store.Archive(ctx, post.ID, now); err != nil {
+			fmt.Printf("Error archiving expired post %s: %v\n", post.ID, err)
+			report.Failed = append(report.Failed, Failure{ID: post.ID, Error: err.Error()})
+			continue
+		}
+		fmt.Printf("Archived expired post %s\n", post.ID)
+		report.Archived = append(report.Archived, post.ID)
+	}
+
+	if len(report.Archived) > 0 {
+		s.Store.Unlist(ctx, report.Archived, now)
+	}
+
+	return report, nil
+}
+
+// DynamoScheduleStore は Publisher のテーブル・検索インデックスを使う ScheduleStore
+type DynamoScheduleStore struct {
+	Publisher *Publisher
+}
+
+// ScheduledDrafts は予約公開の日時が設定された下書きを返す (公開するかは Scheduler が ScheduledDraft.Due で判定する)
+func (s DynamoScheduleStore) ScheduledDrafts(ctx context.Context) ([]ScheduledDraft, error) {
+	var drafts []ScheduledDraft
+	err := scan(ctx, s.Publisher.Client, &dynamodb.ScanInput{
+		TableName:                aws.String(s.Publisher.DraftsTable),
+		FilterExpression:         aws.String("attribute_exists(publishAt)"),
+		ProjectionExpression:     aws.String("id, publishAt, #ttl"),
+		ExpressionAttributeNames: map[string]string{"#ttl": "ttl"},
+	}, &drafts)
+	return drafts, err
+}
+
+func (s DynamoScheduleStore) Publish(ctx context.Context, req Request) (Result, error) {
+	return s.Publisher.Publish(ctx, req)
+}
+
+// ExpiringPosts は公開終了の日時が設定された、まだアーカイブされていない記事を返す
+func (s DynamoScheduleStore) ExpiringPosts(ctx context.Context) ([]ExpiringPost, error) {
+	var expiring []ExpiringPost
+	err := scan(ctx, s.Publisher.Client, &dynamodb.ScanInput{
+		TableName:            aws.String(s.Publisher.PostsTable),
+		FilterExpression:     aws.String("attribute_exists(expireAt) AND (attribute_not_exists(archived) OR archived = :false)"),
+		ProjectionExpression: aws.String("id, expireAt"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":false": &types.AttributeValueMemberBOOL{Value: false},
+		},
+	}, &expiring)
+	return expiring, err
+}
+
+func (s DynamoScheduleStore) Archive(ctx context.Context, id string, now time.Time) error {
+	return posts.Archive(ctx, s.Publisher.Client, s.Publisher.PostsTable, id, now)
+}
+
+// Unlist はアーカイブした記事を検索インデックスから取り除き、他の記事の関連記事から外す
+// 失敗しても rebuild-search-index で作り直せる
+func (s DynamoScheduleStore) Unlist(ctx context.Context, ids []string, now time.Time) {
+	err := s.Publisher.Search.Update(ctx, func(idx *search.Index) {
+		for _, id := range ids {
+			idx.Remove(id)
+		}
+	})
+	if err != nil {
+		fmt.Printf("Error removing archived posts from search index: %v\n", err)
+	}
+
+	if err := UpdateRelated(ctx, s.Publisher.Client, s.Publisher.PostsTable, s.Publisher.Search, now, ids...); err != nil {
+		fmt.Printf("Error updating related posts: %v\n", err)
+	}
+}
+
+// scan はテーブルを最後まで走査して out (スライスへのポインタ) に読み込む
+func scan(ctx context.Context, client *dynamodb.Client, input *dynamodb.ScanInput, out any) error {
+	var items []map[string]types.AttributeValue
+	paginator := dynamodb.NewScanPaginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		items = append(items, page.Items...)
+	}
+	return attributevalue.UnmarshalListOfMaps(items, out)
+}
@@ -0,0 +1,171 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/clock"
+	"github.com/sunshine-724/my-homepage-backend/internal/version"
+)
+
+var schedulerNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeScheduleStore は ScheduleStore をメモリ上で再現する
+type fakeScheduleStore struct {
+	drafts     []ScheduledDraft
+	expiring   []ExpiringPost
+	publishErr map[string]error
+	archiveErr map[string]error
+
+	published []Request
+	archived  []string
+	unlisted  []string
+}
+
+func (f *fakeScheduleStore) ScheduledDrafts(ctx context.Context) ([]ScheduledDraft, error) {
+	return f.drafts, nil
+}
+
+func (f *fakeScheduleStore) Publish(ctx context.Context, req Request) (Result, error) {
+	if err := f.publishErr[req.ID]; err != nil {
+		return Result{}, err
+	}
+	f.published = append(f.published, req)
+	return Result{ID: req.ID, Slug: req.ID + "-slug", Revision: 1}, nil
+}
+
+func (f *fakeScheduleStore) ExpiringPosts(ctx context.Context) ([]ExpiringPost, error) {
+	return f.expiring, nil
+}
+
+func (f *fakeScheduleStore) Archive(ctx context.Context, id string, now time.Time) error {
+	if err := f.archiveErr[id]; err != nil {
+		return err
+	}
+	f.archived = append(f.archived, id)
+	return nil
+}
+
+func (f *fakeScheduleStore) Unlist(ctx context.Context, ids []string, now time.Time) {
+	f.unlisted = append(f.unlisted, ids...)
+}
+
+func TestScheduledDraftDue(t *testing.T) {
+	tests := []struct {
+		name  string
+		draft ScheduledDraft
+		want  bool
+	}{
+		{"past", ScheduledDraft{PublishAt: "2024-05-01T11:59:59Z"}, true},
+		{"exactly now", ScheduledDraft{PublishAt: "2024-05-01T12:00:00Z"}, true},
+		{"future", ScheduledDraft{PublishAt: "2024-05-01T12:00:01Z"}, false},
+		{"no publishAt", ScheduledDraft{}, false},
+		{"ttl in future", ScheduledDraft{PublishAt: "2024-05-01T11:00:00Z", TTL: schedulerNow.Unix() + 1}, true},
+		{"ttl expired", ScheduledDraft{PublishAt: "2024-05-01T11:00:00Z", TTL: schedulerNow.Unix()}, false},
+		{"ttl zero", ScheduledDraft{PublishAt: "2024-05-01T11:00:00Z", TTL: 0}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.draft.Due(schedulerNow); got != tt.want {
+				t.Errorf("Due(%s) = %v, want %v", FormatPublishAt(schedulerNow), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerRun(t *testing.T) {
+	tests := []struct {
+		name          string
+		store         *fakeScheduleStore
+		wantPublished []string
+		wantFailed    []string
+		wantArchived  []string
+	}{
+		{
+			name: "publishes only due drafts",
+			store: &fakeScheduleStore{drafts: []ScheduledDraft{
+				{ID: "due", PublishAt: "2024-05-01T11:00:00Z"},
+				{ID: "now", PublishAt: "2024-05-01T12:00:00Z"},
+				{ID: "later", PublishAt: "2024-05-02T00:00:00Z"},
+				{ID: "expired-ttl", PublishAt: "2024-04-01T00:00:00Z", TTL: schedulerNow.Add(-time.Hour).Unix()},
+			}},
+			wantPublished: []string{"due", "now"},
+		},
+		{
+			name: "skips drafts deleted before publishing",
+			store: &fakeScheduleStore{
+				drafts:     []ScheduledDraft{{ID: "gone", PublishAt: "2024-05-01T11:00:00Z"}, {ID: "due", PublishAt: "2024-05-01T11:00:00Z"}},
+				publishErr: map[string]error{"gone": ErrDraftNotFound},
+			},
+			wantPublished: []string{"due"},
+		},
+		{
+			name: "reports failed drafts and continues",
+			store: &fakeScheduleStore{
+				drafts:     []ScheduledDraft{{ID: "broken", PublishAt: "2024-05-01T11:00:00Z"}, {ID: "due", PublishAt: "2024-05-01T11:00:00Z"}},
+				publishErr: map[string]error{"broken": errors.New("boom")},
+			},
+			wantPublished: []string{"due"},
+			wantFailed:    []string{"broken"},
+		},
+		{
+			name: "archives only expired posts",
+			store: &fakeScheduleStore{expiring: []ExpiringPost{
+				{ID: "expired", ExpireAt: "2024-05-01T11:00:00Z"},
+				{ID: "expires-now", ExpireAt: "2024-05-01T12:00:00Z"},
+				{ID: "still-live", ExpireAt: "2024-05-01T12:00:01Z"},
+			}},
+			wantArchived: []string{"expired", "expires-now"},
+		},
+		{
+			name: "reports failed archives",
+			store: &fakeScheduleStore{
+				expiring:   []ExpiringPost{{ID: "broken", ExpireAt: "2024-05-01T11:00:00Z"}, {ID: "expired", ExpireAt: "2024-05-01T11:00:00Z"}},
+				archiveErr: map[string]error{"broken": errors.New("boom")},
+			},
+			wantFailed:   []string{"broken"},
+			wantArchived: []string{"expired"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Scheduler{Clock: clock.Fixed(schedulerNow), Store: tt.store}
+			report, err := s.Run(context.Background())
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if report.Now != "2024-05-01T12:00:00Z" {
+				t.Errorf("Now = %q", report.Now)
+			}
+
+			var published []string
+			for _, p := range report.Published {
+				published = append(published, p.ID)
+			}
+			var failed []string
+			for _, f := range report.Failed {
+				failed = append(failed, f.ID)
+			}
+			if !reflect.DeepEqual(published, tt.wantPublished) {
+				t.Errorf("Published = %v, want %v", published, tt.wantPublished)
+			}
+			if !reflect.DeepEqual(failed, tt.wantFailed) {
+				t.Errorf("Failed = %v, want %v", failed, tt.wantFailed)
+			}
+			if !reflect.DeepEqual(report.Archived, append([]string{}, tt.wantArchived...)) {
+				t.Errorf("Archived = %v, want %v", report.Archived, tt.wantArchived)
+			}
+			if !reflect.DeepEqual(tt.store.unlisted, tt.store.archived) {
+				t.Errorf("Unlisted = %v, want %v", tt.store.unlisted, tt.store.archived)
+			}
+			for _, req := range tt.store.published {
+				if req.Author != SchedulerAuthor || req.DraftVersion != version.Any || !req.IsPublished {
+					t.Errorf("Publish request = %+v", req)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package revision
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op: 差分の行の種類
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+// Line: 行単位の差分の1行
+type Line struct {
+	Op      Op     `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"oldLine,omitempty"` // 変更前の行番号 (1始まり、insertの場合は0)
+	NewLine int    `json:"newLine,omitempty"` // 変更後の行番号 (1始まり、deleteの場合は0)
+}
+
+// maxEdits: Myers のアルゴリズムで探索する編集 (挿入・削除) の数の上限
+// 探索の途中経過は編集の数の2乗に比例するメモリを使うため、これを超える場合は変更部分をまとめて置き換えた差分にする
+const maxEdits = 2000
+
+// Diff は2つのテキストの行単位の差分を返す (Myers の O(ND) アルゴリズムによる最短の編集)
+func Diff(before, after string) []Line {
+	a := splitLines(before)
+	b := splitLines(after)
+
+	// 共通の先頭・末尾を除いてから差分を計算する
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []Line
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, Line{Op: OpEqual, Text: a[i], OldLine: i + 1, NewLine: i + 1})
+	}
+	lines = append(lines, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := 0; i < suffix; i++ {
+		oi := len(a) - suffix + i
+		ni := len(b) - suffix + i
+		lines = append(lines, Line{Op: OpEqual, Text: a[oi], OldLine: oi + 1, NewLine: ni + 1})
+	}
+
+	return lines
+}
+
+// myersDiff は a を b にする最短の編集を Myers のアルゴリズムで求める
+// trace[d][k+d] は d 回の編集で対角線 k (x - y = k) 上で到達できる最も遠い x
+// 編集の数が maxEdits を超える場合は replaceDiff にする
+func myersDiff(a, b []string, oldOffset, newOffset int) []Line {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceDiff(a, b, oldOffset, newOffset)
+	}
+
+	var trace [][]int32
+	furthest := func(d, k int) int { return int(trace[d][k+d]) }
+	for d := 0; d <= min(n+m, maxEdits); d++ {
+		v := make([]int32, 2*d+1)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			switch {
+			case d == 0:
+				x = 0
+			case k == -d || (k != d && furthest(d-1, k-1) < furthest(d-1, k+1)):
+				x = furthest(d-1, k+1) // 挿入 (b の行を1つ進める)
+			default:
+				x = furthest(d-1, k-1) + 1 // 削除 (a の行を1つ進める)
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+d] = int32(x)
+			if x >= n && y >= m {
+				trace = append(trace, v)
+				return backtrack(trace, a, b, oldOffset, newOffset)
+			}
+		}
+		trace = append(trace, v)
+	}
+
+	fmt.Printf("Diff exceeds %d edits, falling back to a full replace (%d -> %d lines)\n", maxEdits, n, m)
+	return replaceDiff(a, b, oldOffset, newOffset)
+}
+
+// backtrack は探索の途中経過を終点から辿って差分の行を組み立てる
+func backtrack(trace [][]int32, a, b []string, oldOffset, newOffset int) []Line {
+	furthest := func(d, k int) int { return int(trace[d][k+d]) }
+	var reversed []Line
+	equal := func(x, y int) {
+		reversed = append(reversed, Line{Op: OpEqual, Text: a[x], OldLine: oldOffset + x + 1, NewLine: newOffset + y + 1})
+	}
+
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && furthest(d-1, k-1) < furthest(d-1, k+1)) {
+			prevK = k + 1
+		}
+		prevX := furthest(d-1, prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			equal(x, y)
+		}
+		if prevK == k+1 {
+			y--
+			reversed = append(reversed, Line{Op: OpInsert, Text: b[y], NewLine: newOffset + y + 1})
+		} else {
+			x--
+			reversed = append(reversed, Line{Op: OpDelete, Text: a[x], OldLine: oldOffset + x + 1})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		equal(x, y)
+	}
+
+	lines := make([]Line, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+// replaceDiff は a をすべて削除して b をすべて挿入する差分を返す
+func replaceDiff(a, b []string, oldOffset, newOffset int) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+	for i, text := range a {
+		lines = append(lines, Line{Op: OpDelete, Text: text, OldLine: oldOffset + i + 1})
+	}
+	for j, text := range b {
+		lines = append(lines, Line{Op: OpInsert, Text: text, NewLine: newOffset + j + 1})
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
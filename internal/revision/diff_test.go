@@ -0,0 +1,118 @@
+package revision
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          []Line
+	}{
+		{"empty", "", "", nil},
+		{"identical", "a\nb\n", "a\nb\n", []Line{
+			{Op: OpEqual, Text: "a", OldLine: 1, NewLine: 1},
+			{Op: OpEqual, Text: "b", OldLine: 2, NewLine: 2},
+		}},
+		{"insert into empty", "", "a\nb", []Line{
+			{Op: OpInsert, Text: "a", NewLine: 1},
+			{Op: OpInsert, Text: "b", NewLine: 2},
+		}},
+		{"delete all", "a\nb", "", []Line{
+			{Op: OpDelete, Text: "a", OldLine: 1},
+			{Op: OpDelete, Text: "b", OldLine: 2},
+		}},
+		{"pure insert", "a\nc", "a\nb\nc", []Line{
+			{Op: OpEqual, Text: "a", OldLine: 1, NewLine: 1},
+			{Op: OpInsert, Text: "b", NewLine: 2},
+			{Op: OpEqual, Text: "c", OldLine: 2, NewLine: 3},
+		}},
+		{"pure delete", "a\nb\nc", "a\nc", []Line{
+			{Op: OpEqual, Text: "a", OldLine: 1, NewLine: 1},
+			{Op: OpDelete, Text: "b", OldLine: 2},
+			{Op: OpEqual, Text: "c", OldLine: 3, NewLine: 2},
+		}},
+		{"replace", "a\nb\nc", "a\nx\nc", []Line{
+			{Op: OpEqual, Text: "a", OldLine: 1, NewLine: 1},
+			{Op: OpDelete, Text: "b", OldLine: 2},
+			{Op: OpInsert, Text: "x", NewLine: 2},
+			{Op: OpEqual, Text: "c", OldLine: 3, NewLine: 3},
+		}},
+		{"crlf", "a\r\nb\r\n", "a\nb\n", []Line{
+			{Op: OpEqual, Text: "a", OldLine: 1, NewLine: 1},
+			{Op: OpEqual, Text: "b", OldLine: 2, NewLine: 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff(%q, %q) = %+v, want %+v", tt.before, tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffIsMinimal は差分から両方のテキストを復元でき、編集の数が最小であることを確かめる
+func TestDiffIsMinimal(t *testing.T) {
+	tests := []struct {
+		before, after string
+		edits         int
+	}{
+		{"a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc", 5},
+		{"x\na\ny\nb\nz", "a\nq\nb", 4},
+		{"1\n2\n3\n4\n5", "5\n4\n3\n2\n1", 8},
+	}
+	for _, tt := range tests {
+		lines := Diff(tt.before, tt.after)
+		before, after, edits := apply(lines)
+		if before != tt.before || after != tt.after {
+			t.Errorf("Diff(%q, %q) reconstructs %q, %q", tt.before, tt.after, before, after)
+		}
+		if edits != tt.edits {
+			t.Errorf("Diff(%q, %q) has %d edits, want %d", tt.before, tt.after, edits, tt.edits)
+		}
+	}
+}
+
+// TestDiffLarge は編集の数が上限を超える大きな差分でも、置き換えにして妥当な差分を返すことを確かめる
+func TestDiffLarge(t *testing.T) {
+	var before, after []string
+	for i := 0; i < 10000; i++ {
+		before = append(before, fmt.Sprintf("old %d", i))
+		after = append(after, fmt.Sprintf("new %d", i))
+	}
+	lines := Diff(strings.Join(before, "\n"), strings.Join(after, "\n"))
+	if len(lines) != 20000 {
+		t.Fatalf("len(Diff) = %d, want 20000", len(lines))
+	}
+	b, a, _ := apply(lines)
+	if b != strings.Join(before, "\n") || a != strings.Join(after, "\n") {
+		t.Error("large diff does not reconstruct the inputs")
+	}
+}
+
+// apply は差分から変更前・変更後のテキストと編集の数を復元し、行番号が連続していることを確かめる
+func apply(lines []Line) (before, after string, edits int) {
+	var b, a []string
+	for _, line := range lines {
+		switch line.Op {
+		case OpEqual:
+			b = append(b, line.Text)
+			a = append(a, line.Text)
+		case OpDelete:
+			b = append(b, line.Text)
+			edits++
+		case OpInsert:
+			a = append(a, line.Text)
+			edits++
+		}
+		if (line.OldLine != 0 && line.OldLine != len(b)) || (line.NewLine != 0 && line.NewLine != len(a)) {
+			return "", "", -1
+		}
+	}
+	return strings.Join(b, "\n"), strings.Join(a, "\n"), edits
+}
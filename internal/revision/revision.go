@@ -0,0 +1,181 @@
+// Package revision は下書き・記事の保存ごとに作る不変のリビジョン (履歴) を扱う
+//
+// リビジョンは REVISIONS_TABLE_NAME (パーティションキー postId、ソートキー rev) に保存する。
+// 下書きと記事は同じIDを使うため、下書きの編集から公開後の変更までが1つの履歴になる。
+package revision
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Source: リビジョンを作った操作
+type Source string
+
+const (
+	SourceDraft   Source = "draft"   // 下書きの作成・更新
+	SourcePublish Source = "publish" // 公開
+	SourceRestore Source = "restore" // 以前のリビジョンへのロールバック
+)
+
+// Revision: リビジョンのアイテム
+type Revision struct {
+	PostID      string   `json:"postId" dynamodbav:"postId"`
+	Rev         int      `json:"rev" dynamodbav:"rev"`
+	Title       string   `json:"title" dynamodbav:"title"`
+	Date        string   `json:"date" dynamodbav:"date"`
+	Content     string   `json:"content,omitempty" dynamodbav:"content"`
+	Tags        []string `json:"tags" dynamodbav:"tags"`
+	Author      string   `json:"author" dynamodbav:"author"`
+	Source      Source   `json:"source" dynamodbav:"source"`
+	ContentHash string   `json:"contentHash" dynamodbav:"contentHash"` // contentのSHA-256
+	CreatedAt   string   `json:"createdAt" dynamodbav:"createdAt"`     // RFC 3339
+
+	RestoredFrom int `json:"restoredFrom,omitempty" dynamodbav:"restoredFrom,omitempty"` // ロールバックの場合、復元元のリビジョン
+}
+
+// New は保存する内容からリビジョンを作る (Revは Next で採番した値を設定する)
+func New(postID string, rev int, title, date, content string, tags []string, author string, source Source) Revision {
+	return Revision{
+		PostID:      postID,
+		Rev:         rev,
+		Title:       title,
+		Date:        date,
+		Content:     content,
+		Tags:        tags,
+		Author:      author,
+		Source:      source,
+		ContentHash: Hash(content),
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// Hash は本文のハッシュを返す
+func Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Next は次のリビジョン番号を返す (リビジョンが無い場合は1)
+func Next(ctx context.Context, client *dynamodb.Client, tableName, postID string) (int, error) {
+	result, err := client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("postId = :postId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":postId": &types.AttributeValueMemberS{Value: postID},
+		},
+		ProjectionExpression: aws.String("rev"),
+		ScanIndexForward:     aws.Bool(false),
+		Limit:                aws.Int32(1),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(result.Items) == 0 {
+		return 1, nil
+	}
+
+	var latest struct {
+		Rev int `dynamodbav:"rev"`
+	}
+	if err := attributevalue.UnmarshalMap(result.Items[0], &latest); err != nil {
+		return 0, err
+	}
+	return latest.Rev + 1, nil
+}
+
+// Put はリビジョンを書き込むトランザクションの操作を返す
+// 下書き・記事の保存と同じ TransactWriteItems に含めて、保存とリビジョンの記録を同時に行う
+// 同じ番号のリビジョンが既に存在する場合 (同時に保存された場合) はトランザクション全体が失敗する
+func Put(tableName string, rev Revision) (types.TransactWriteItem, error) {
+	av, err := attributevalue.MarshalMap(rev)
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(tableName),
+			Item:                av,
+			ConditionExpression: aws.String("attribute_not_exists(rev)"),
+		},
+	}, nil
+}
+
+// List は記事のリビジョンを新しい順に返す (本文は含めない)
+func List(ctx context.Context, client *dynamodb.Client, tableName, postID string) ([]Revision, error) {
+	var revisions []Revision
+
+	paginator := dynamodb.NewQueryPaginator(client, &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("postId = :postId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":postId": &types.AttributeValueMemberS{Value: postID},
+		},
+		ProjectionExpression: aws.String("postId, rev, title, #date, tags, author, #source, contentHash, createdAt, restoredFrom"),
+		ExpressionAttributeNames: map[string]string{
+			"#date":   "date",
+			"#source": "source",
+		},
+		ScanIndexForward: aws.Bool(false),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var items []Revision
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, items...)
+	}
+
+	return revisions, nil
+}
+
+// Get はリビジョンを1件取得する (存在しない場合は nil)
+func Get(ctx context.Context, client *dynamodb.Client, tableName, postID string, rev int) (*Revision, error) {
+	result, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"postId": &types.AttributeValueMemberS{Value: postID},
+			"rev":    &types.AttributeValueMemberN{Value: strconv.Itoa(rev)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var revision Revision
+	if err := attributevalue.UnmarshalMap(result.Item, &revision); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// AuthorFromRequest はリクエストから保存した人を特定する
+// 管理画面 (Next.js API Route) が付ける X-Author ヘッダーを優先し、無ければAPIキーのIDを使う
+func AuthorFromRequest(request events.APIGatewayProxyRequest) string {
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, "X-Author") && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	if id := request.RequestContext.Identity.APIKeyID; id != "" {
+		return "apikey:" + id
+	}
+	return "unknown"
+}
@@ -0,0 +1,348 @@
+// Package search は公開済みの記事の全文検索 (転置インデックスとBM25によるランキング) を扱う
+//
+// インデックスは1つのJSONとして検索用の非公開のS3バケット (SEARCH_BUCKET_NAME) に置き、
+// 公開・公開の取り消し・アーカイブ・復元のたびに該当する記事だけを更新する。
+// 入力補完の候補 (Suggestions) は読み出したインデックスから作る。
+// 全体の作り直しは rebuild-search-index で行う。
+package search
+
+import (
+	"html"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/markdown"
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+	"github.com/sunshine-724/my-homepage-backend/internal/textstat"
+)
+
+// フィールドごとの重み (タイトルとタグに一致した記事を上位にする)
+const (
+	titleWeight   = 3
+	tagWeight     = 2
+	contentWeight = 1
+)
+
+// BM25のパラメータ
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// snippetLength: スニペットの最大文字数
+const snippetLength = 120
+
+// Index: 転置インデックス
+type Index struct {
+	Docs  map[string]*Doc      `json:"docs"`
+	Terms map[string][]Posting `json:"terms"` // 語 → その語を含む記事
+}
+
+// Doc: インデックスに登録された記事
+type Doc struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Slug     string   `json:"slug,omitempty"`
+	Date     string   `json:"date"`
+	Tags     []string `json:"tags,omitempty"`
+	ExpireAt string   `json:"expireAt,omitempty"`
+	Text     string   `json:"text"`   // スニペットに使うプレーンテキストの本文
+	Length   float64  `json:"length"` // 重み付きの語数 (BM25の文書長)
+	Terms    []string `json:"terms"`  // 記事に含まれる語 (削除時に使う)
+	Words    []string `json:"words"`  // 入力補完の候補にする語 (vocabulary)
+}
+
+// Posting: 語を含む記事と、重み付きの出現回数
+type Posting struct {
+	ID string  `json:"id"`
+	TF float64 `json:"tf"`
+}
+
+// Document: インデックスに登録する記事
+type Document struct {
+	ID       string
+	Title    string
+	Slug     string
+	Date     string
+	Tags     []string
+	Content  string // Markdown の本文
+	ExpireAt string
+}
+
+// FromPost は投稿テーブルの記事からインデックスに登録する記事を作る
+func FromPost(item posts.Item) Document {
+	return Document{
+		ID:       item.ID,
+		Title:    item.Title,
+		Slug:     item.Slug,
+		Date:     item.Date,
+		Tags:     item.Tags,
+		Content:  item.Content,
+		ExpireAt: item.ExpireAt,
+	}
+}
+
+// New は空のインデックスを作る
+func New() *Index {
+	return &Index{Docs: map[string]*Doc{}, Terms: map[string][]Posting{}}
+}
+
+// Put は記事をインデックスに登録する (登録済みの場合は置き換える)
+func (idx *Index) Put(doc Document) {
+	idx.Remove(doc.ID)
+
+	prose, code := markdown.PlainText(doc.Content)
+	tf := map[string]float64{}
+	add := func(text string, weight float64) {
+		for _, token := range Tokenize(text) {
+			tf[token] += weight
+		}
+	}
+	add(doc.Title, titleWeight)
+	for _, tag := range doc.Tags {
+		add(tag, tagWeight)
+	}
+	add(prose, contentWeight)
+	add(code, contentWeight)
+
+	d := &Doc{
+		ID:       doc.ID,
+		Title:    doc.Title,
+		Slug:     doc.Slug,
+		Date:     doc.Date,
+		Tags:     doc.Tags,
+		ExpireAt: doc.ExpireAt,
+		Text:     textstat.Normalize(prose),
+	}
+	for term, count := range tf {
+		idx.Terms[term] = append(idx.Terms[term], Posting{ID: doc.ID, TF: count})
+		d.Terms = append(d.Terms, term)
+		d.Length += count
+	}
+	sort.Strings(d.Terms)
+
+	words := map[string]bool{}
+	for _, text := range []string{doc.Title, prose} {
+		for _, word := range vocabulary(text) {
+			words[word] = true
+		}
+	}
+	for word := range words {
+		d.Words = append(d.Words, word)
+	}
+	sort.Strings(d.Words)
+
+	idx.Docs[doc.ID] = d
+}
+
+// Remove は記事をインデックスから取り除く (登録されていない場合は何もしない)
+func (idx *Index) Remove(id string) {
+	d, ok := idx.Docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range d.Terms {
+		postings := slices.DeleteFunc(idx.Terms[term], func(p Posting) bool { return p.ID == id })
+		if len(postings) == 0 {
+			delete(idx.Terms, term)
+		} else {
+			idx.Terms[term] = postings
+		}
+	}
+	delete(idx.Docs, id)
+}
+
+// Hit: 検索結果の1件
+type Hit struct {
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	Slug    string   `json:"slug,omitempty"`
+	Date    string   `json:"date"`
+	Tags    []string `json:"tags,omitempty"`
+	Score   float64  `json:"score"`
+	Snippet string   `json:"snippet"` // 一致した箇所を <mark> で囲んだHTML (それ以外はエスケープ済み)
+}
+
+// Search は検索語のすべての語を含む記事をスコアの高い順に返す
+// 公開終了の日時を過ぎた記事は含めない
+func (idx *Index) Search(query string, now time.Time) []Hit {
+	tokens := Tokenize(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	n := float64(len(idx.Docs))
+	avgLength := 0.0
+	for _, d := range idx.Docs {
+		avgLength += d.Length
+	}
+	if n > 0 {
+		avgLength /= n
+	}
+
+	scores := map[string]float64{}
+	matched := map[string]int{}
+	seen := map[string]bool{}
+	clauses := 0
+	for _, token := range tokens {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+		clauses++
+
+		postings := idx.postings(token)
+		idf := math.Log(1 + (n-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+		for _, p := range postings {
+			length := idx.Docs[p.ID].Length
+			scores[p.ID] += idf * p.TF * (k1 + 1) / (p.TF + k1*(1-b+b*length/avgLength))
+			matched[p.ID]++
+		}
+	}
+
+	terms := highlightTerms(query)
+	var hits []Hit
+	for id, score := range scores {
+		d := idx.Docs[id]
+		if matched[id] < clauses || posts.Expired(d.ExpireAt, now) {
+			continue
+		}
+		hits = append(hits, Hit{
+			ID:      d.ID,
+			Title:   d.Title,
+			Slug:    d.Slug,
+			Date:    d.Date,
+			Tags:    d.Tags,
+			Score:   math.Round(score*1000) / 1000,
+			Snippet: Snippet(d.Text, terms, snippetLength),
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Date != hits[j].Date {
+			return hits[i].Date > hits[j].Date
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// postings は語を含む記事を返す
+// 1文字だけのCJKの語 (索引にはバイグラムしか無い) は、その文字を含むすべての語の和とする
+func (idx *Index) postings(token string) []Posting {
+	if postings, ok := idx.Terms[token]; ok {
+		return postings
+	}
+	runes := []rune(token)
+	if len(runes) != 1 || !textstat.IsCJK(runes[0]) {
+		return nil
+	}
+
+	tf := map[string]float64{}
+	for term, postings := range idx.Terms {
+		if !strings.Contains(term, token) {
+			continue
+		}
+		for _, p := range postings {
+			tf[p.ID] = max(tf[p.ID], p.TF)
+		}
+	}
+	result := make([]Posting, 0, len(tf))
+	for id, count := range tf {
+		result = append(result, Posting{ID: id, TF: count})
+	}
+	return result
+}
+
+// highlightTerms はスニペットで強調する文字列 (検索語の空白区切りの各語と、英単語の語幹) を返す
+func highlightTerms(query string) []string {
+	var terms []string
+	for _, r := range runs(query) {
+		word := string(r.runes)
+		terms = append(terms, word)
+		if !r.cjk {
+			if stem := Stem(word); stem != word {
+				terms = append(terms, stem)
+			}
+		}
+	}
+	return terms
+}
+
+// Snippet は本文から最初に一致した箇所の前後を切り出し、一致した文字列を <mark> で囲む
+// 一致する箇所が無い場合は本文の先頭を返す
+func Snippet(text string, terms []string, length int) string {
+	runes := []rune(text)
+	folded := make([]rune, len(runes))
+	for i, r := range runes {
+		folded[i] = Fold(r)
+	}
+
+	// 一致した範囲 (英単語は語幹から単語の終わりまで)
+	type span struct{ start, end int }
+	var spans []span
+	for i := 0; i < len(folded); {
+		best := 0
+		for _, term := range terms {
+			t := []rune(term)
+			if len(t) > best && hasPrefixAt(folded, t, i) {
+				best = len(t)
+			}
+		}
+		if best == 0 {
+			i++
+			continue
+		}
+		end := i + best
+		// CJKの語は単語の区切りが無いため、一致した範囲だけを強調する
+		for end < len(folded) && isWordRune(folded[end-1]) && !textstat.IsCJK(folded[end-1]) && isWordRune(folded[end]) && !textstat.IsCJK(folded[end]) {
+			end++
+		}
+		spans = append(spans, span{i, end})
+		i = end
+	}
+
+	start := 0
+	if len(spans) > 0 {
+		start = max(0, spans[0].start-length/4)
+	}
+	end := min(len(runes), start+length)
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	pos := start
+	for _, s := range spans {
+		if s.end <= pos || s.start >= end {
+			continue
+		}
+		sb.WriteString(html.EscapeString(string(runes[pos:max(pos, s.start)])))
+		sb.WriteString("<mark>")
+		sb.WriteString(html.EscapeString(string(runes[max(pos, s.start):min(end, s.end)])))
+		sb.WriteString("</mark>")
+		pos = min(end, s.end)
+	}
+	sb.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
+
+func hasPrefixAt(s, prefix []rune, i int) bool {
+	if i+len(prefix) > len(s) {
+		return false
+	}
+	return slices.Equal(s[i:i+len(prefix)], prefix)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+var searchNow = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func testIndex() *Index {
+	idx := New()
+	idx.Put(Document{ID: "title", Title: "Go testing", Date: "2024-01-01", Content: "Notes about tables."})
+	idx.Put(Document{ID: "body", Title: "Notes", Date: "2024-02-01", Content: "Some testing of Go code."})
+	idx.Put(Document{ID: "tag", Title: "Misc", Date: "2024-03-01", Tags: []string{"testing"}, Content: "Go is fun."})
+	idx.Put(Document{ID: "tokyo", Title: "東京の旅行", Date: "2024-04-01", Content: "東京タワーに行きました。"})
+	idx.Put(Document{ID: "expired", Title: "Go testing again", Date: "2024-04-02", ExpireAt: "2024-04-30T00:00:00Z"})
+	return idx
+}
+
+func ids(hits []Hit) []string {
+	var result []string
+	for _, h := range hits {
+		result = append(result, h.ID)
+	}
+	return result
+}
+
+func TestSearchRanksByField(t *testing.T) {
+	hits := testIndex().Search("testing", searchNow)
+	got := ids(hits)
+	// タイトル (重み3) > タグ (重み2) > 本文 (重み1) の順。公開終了した記事は含めない
+	want := []string{"title", "tag", "body"}
+	if len(got) != len(want) {
+		t.Fatalf("Search = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Search = %v, want %v", got, want)
+		}
+	}
+	for i := 1; i < len(hits); i++ {
+		if hits[i].Score > hits[i-1].Score {
+			t.Errorf("scores not descending: %v", hits)
+		}
+	}
+}
+
+func TestSearchRequiresAllTerms(t *testing.T) {
+	got := ids(testIndex().Search("go tables", searchNow))
+	if len(got) != 1 || got[0] != "title" {
+		t.Errorf("Search = %v, want [title]", got)
+	}
+}
+
+func TestSearchIDF(t *testing.T) {
+	// どの記事にも含まれる語より、少ない記事にしか含まれない語の方がスコアが高い
+	idx := New()
+	idx.Put(Document{ID: "a", Title: "alpha common"})
+	idx.Put(Document{ID: "b", Title: "beta common"})
+	idx.Put(Document{ID: "c", Title: "gamma common"})
+	rare := idx.Search("alpha", searchNow)
+	common := idx.Search("common", searchNow)
+	if len(rare) != 1 || len(common) != 3 {
+		t.Fatalf("rare = %v, common = %v", ids(rare), ids(common))
+	}
+	if rare[0].Score <= common[0].Score {
+		t.Errorf("rare score %v <= common score %v", rare[0].Score, common[0].Score)
+	}
+}
+
+func TestSearchCJK(t *testing.T) {
+	idx := testIndex()
+	for _, query := range []string{"東京", "東京タワー", "京"} {
+		got := ids(idx.Search(query, searchNow))
+		if len(got) != 1 || got[0] != "tokyo" {
+			t.Errorf("Search(%q) = %v, want [tokyo]", query, got)
+		}
+	}
+	if got := idx.Search("大阪", searchNow); len(got) != 0 {
+		t.Errorf("Search(大阪) = %v, want none", ids(got))
+	}
+}
+
+func TestRemove(t *testing.T) {
+	idx := testIndex()
+	idx.Remove("tokyo")
+	if got := idx.Search("東京", searchNow); len(got) != 0 {
+		t.Errorf("Search after Remove = %v", ids(got))
+	}
+	for term, postings := range idx.Terms {
+		for _, p := range postings {
+			if p.ID == "tokyo" {
+				t.Errorf("term %q still has posting for removed doc", term)
+			}
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{"word", "I like testing code", []string{"test"}, "I like <mark>testing</mark> code"},
+		{"cjk stops at match", "東京abcに行く", []string{"東京"}, "<mark>東京</mark>abcに行く"},
+		{"escapes html", "<b>go</b>", []string{"go"}, "&lt;b&gt;<mark>go</mark>&lt;/b&gt;"},
+		{"no match", "hello world", []string{"zzz"}, "hello world"},
+		{"full width", "ＧＯ is fun", []string{"go"}, "<mark>ＧＯ</mark> is fun"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Snippet(tt.text, tt.terms, 120); got != tt.want {
+				t.Errorf("Snippet = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
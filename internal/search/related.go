@@ -0,0 +1,173 @@
+package search
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+)
+
+// 関連度 = relatedTagWeight × タグのJaccard係数 + (1 - relatedTagWeight) × 本文のTF-IDFのコサイン類似度
+const (
+	relatedTagWeight = 0.4
+	relatedMinScore  = 0.05 // これより関連度が低い記事は関連記事にしない
+)
+
+// Related はインデックスに登録されたすべての記事について、関連度の高い順に最大 n 件の関連記事を返す
+// 公開終了の日時を過ぎた記事は関連記事に含めない
+// すべての記事の組を比べるため、記事の公開のたびではなく、インデックスの作り直し (rebuild-search-index) で使う
+func (idx *Index) Related(n int, now time.Time) map[string][]posts.Related {
+	similarity := idx.cosineSimilarity()
+
+	result := make(map[string][]posts.Related, len(idx.Docs))
+	for id := range idx.Docs {
+		result[id] = idx.top(id, n, now, func(otherID string) float64 { return similarity[pair(id, otherID)] })
+	}
+	return result
+}
+
+// RelatedTo は1件の記事について、関連度の高い順に最大 n 件の関連記事を返す (記事がインデックスに無い場合は nil)
+// その記事とほかの記事の組だけを比べるため、記事の数に比例する時間で済む (Related の id の結果と同じになる)
+func (idx *Index) RelatedTo(id string, n int, now time.Time) []posts.Related {
+	if _, ok := idx.Docs[id]; !ok {
+		return nil
+	}
+	similarity := idx.similarityTo(id)
+	return idx.top(id, n, now, func(otherID string) float64 { return similarity[otherID] })
+}
+
+// top は記事 id について、関連度の高い順に最大 n 件の関連記事を返す
+// similarity はほかの記事との本文のコサイン類似度
+func (idx *Index) top(id string, n int, now time.Time, similarity func(otherID string) float64) []posts.Related {
+	d := idx.Docs[id]
+	type candidate struct {
+		doc   *Doc
+		score float64
+	}
+	var candidates []candidate
+	for otherID, other := range idx.Docs {
+		if otherID == id || posts.Expired(other.ExpireAt, now) {
+			continue
+		}
+		score := relatedTagWeight*jaccard(d.Tags, other.Tags) + (1-relatedTagWeight)*similarity(otherID)
+		if score >= relatedMinScore {
+			candidates = append(candidates, candidate{other, score})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		if candidates[i].doc.Date != candidates[j].doc.Date {
+			return candidates[i].doc.Date > candidates[j].doc.Date
+		}
+		return candidates[i].doc.ID < candidates[j].doc.ID
+	})
+
+	related := []posts.Related{}
+	for _, c := range candidates[:min(n, len(candidates))] {
+		related = append(related, posts.Related{ID: c.doc.ID, Title: c.doc.Title, Slug: c.doc.Slug})
+	}
+	return related
+}
+
+// pair は2つの記事の組のキー (順序によらない)
+func pair(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// cosineSimilarity は記事の組ごとのTF-IDFのベクトルのコサイン類似度を求める
+// 転置インデックスの語ごとに、その語を含む記事の組の内積を足し合わせる (similarityTerm の語だけを使う)
+func (idx *Index) cosineSimilarity() map[[2]string]float64 {
+	n := float64(len(idx.Docs))
+	dot := map[[2]string]float64{}
+	norm := map[string]float64{}
+	for _, postings := range idx.Terms {
+		weights := idx.weights(postings, norm)
+		if !similarityTerm(len(postings), n) {
+			continue
+		}
+		for i := range postings {
+			for j := i + 1; j < len(postings); j++ {
+				dot[pair(postings[i].ID, postings[j].ID)] += weights[i] * weights[j]
+			}
+		}
+	}
+
+	for key, value := range dot {
+		if denominator := math.Sqrt(norm[key[0]] * norm[key[1]]); denominator > 0 {
+			dot[key] = value / denominator
+		}
+	}
+	return dot
+}
+
+// similarityTo は記事 id とほかの記事のTF-IDFのベクトルのコサイン類似度を求める (cosineSimilarity と同じ値)
+// 内積はその記事に含まれる語の転置インデックスだけを辿り、ノルムは全記事について1回ずつ求める
+func (idx *Index) similarityTo(id string) map[string]float64 {
+	n := float64(len(idx.Docs))
+	norm := map[string]float64{}
+	for _, postings := range idx.Terms {
+		idx.weights(postings, norm)
+	}
+
+	dot := map[string]float64{}
+	for _, term := range idx.Docs[id].Terms {
+		postings := idx.Terms[term]
+		if !similarityTerm(len(postings), n) {
+			continue
+		}
+		weights := idx.weights(postings, nil)
+		own := weights[slices.IndexFunc(postings, func(p Posting) bool { return p.ID == id })]
+		for i, p := range postings {
+			if p.ID != id {
+				dot[p.ID] += own * weights[i]
+			}
+		}
+	}
+
+	for otherID, value := range dot {
+		if denominator := math.Sqrt(norm[id] * norm[otherID]); denominator > 0 {
+			dot[otherID] = value / denominator
+		}
+	}
+	return dot
+}
+
+// weights は語を含む記事ごとのTF-IDFの重みを返す (norm が nil でなければ各記事のノルムの2乗に足す)
+func (idx *Index) weights(postings []Posting, norm map[string]float64) []float64 {
+	idf := math.Log(float64(len(idx.Docs)) / float64(len(postings)))
+	weights := make([]float64, len(postings))
+	for i, p := range postings {
+		weights[i] = (1 + math.Log(p.TF)) * idf
+		if norm != nil {
+			norm[p.ID] += weights[i] * weights[i]
+		}
+	}
+	return weights
+}
+
+// similarityTerm は語を類似度の計算に使うかを返す
+// 1つの記事にしか含まれない語は組を作らず、半数を超える記事に含まれる語 (「する」「ます」など) はほとんど効かないため飛ばす
+func similarityTerm(df int, n float64) bool {
+	return df >= 2 && float64(df) <= n/2
+}
+
+// jaccard はタグの集合のJaccard係数を求める
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for _, tag := range a {
+		if slices.Contains(b, tag) {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
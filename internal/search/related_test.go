@@ -0,0 +1,63 @@
+package search
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func relatedIndex() *Index {
+	idx := New()
+	idx.Put(Document{ID: "go-test", Title: "Go testing", Date: "2024-01-01", Tags: []string{"go"}, Content: "Table driven tests in Go with subtests."})
+	idx.Put(Document{ID: "go-bench", Title: "Go benchmarks", Date: "2024-01-02", Tags: []string{"go"}, Content: "Benchmarks and tests for Go code."})
+	idx.Put(Document{ID: "go-mod", Title: "Go modules", Date: "2024-01-03", Tags: []string{"go"}, Content: "Versioning modules and dependencies."})
+	idx.Put(Document{ID: "ramen", Title: "ラーメンの作り方", Date: "2024-02-01", Tags: []string{"料理"}, Content: "スープと麺の茹で方。"})
+	idx.Put(Document{ID: "udon", Title: "うどんの作り方", Date: "2024-02-02", Tags: []string{"料理"}, Content: "麺の茹で方とつゆ。"})
+	idx.Put(Document{ID: "travel", Title: "Travel notes", Date: "2024-03-01", Content: "Trains and hotels."})
+	idx.Put(Document{ID: "expired", Title: "Go testing old", Date: "2023-01-01", Tags: []string{"go"}, Content: "Table driven tests.", ExpireAt: "2024-01-01T00:00:00Z"})
+	return idx
+}
+
+func relatedIDs(idx *Index, id string, now time.Time) []string {
+	var ids []string
+	for _, r := range idx.RelatedTo(id, 5, now) {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestRelatedToMatchesRelated(t *testing.T) {
+	idx := relatedIndex()
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	all := idx.Related(5, now)
+	for id := range idx.Docs {
+		if got := idx.RelatedTo(id, 5, now); !slices.Equal(got, all[id]) {
+			t.Errorf("RelatedTo(%s) = %v, Related()[%s] = %v", id, got, id, all[id])
+		}
+	}
+}
+
+func TestRelatedTo(t *testing.T) {
+	idx := relatedIndex()
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	got := relatedIDs(idx, "go-test", now)
+	if len(got) == 0 || got[0] != "go-bench" {
+		t.Errorf("RelatedTo(go-test) = %v, want go-bench first", got)
+	}
+	if slices.Contains(got, "expired") || slices.Contains(got, "ramen") || slices.Contains(got, "go-test") {
+		t.Errorf("RelatedTo(go-test) = %v, must not contain expired, unrelated or itself", got)
+	}
+	if got := relatedIDs(idx, "ramen", now); !slices.Equal(got, []string{"udon"}) {
+		t.Errorf("RelatedTo(ramen) = %v, want [udon]", got)
+	}
+	if got := idx.RelatedTo("travel", 5, now); got == nil || len(got) != 0 {
+		t.Errorf("RelatedTo(travel) = %#v, want empty", got)
+	}
+	if got := idx.RelatedTo("missing", 5, now); got != nil {
+		t.Errorf("RelatedTo(missing) = %v, want nil", got)
+	}
+	if got := idx.RelatedTo("go-test", 1, now); len(got) != 1 {
+		t.Errorf("RelatedTo(go-test, 1) = %v, want 1 entry", got)
+	}
+}
@@ -0,0 +1,213 @@
+package search
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// インデックスと入力補完の候補は、公開されうる添付ファイルのバケットとは別の非公開のバケット (SEARCH_BUCKET_NAME) に置く
+// 同じバケットを指定された場合にも添付ファイルと区別できるよう、"_" で始まる予約済みのプレフィックスを使う
+const DefaultKey = "_index/search.json" // インデックスを置くS3のキー
+
+// maxUpdateAttempts: 同時に更新された場合に再試行する回数
+const maxUpdateAttempts = 5
+
+// Store はS3に置いたインデックスを読み書きし、入力補完の候補をインデックスから作る
+// 読み出したインデックスはETagと一緒にキャッシュし、変更されていなければ再利用する (Lambdaの実行環境の間で使い回す)
+// Bucket が空の場合、Update・Replace は何もせず、Load・LoadSuggestions は空を返す
+type Store struct {
+	Client *s3.Client
+	Bucket string
+	Key    string // 空の場合は DefaultKey
+
+	mu          sync.Mutex
+	index       cached[Index]
+	suggestions cached[Suggestions] // etag は作り元のインデックスのETag
+}
+
+// StoreFromEnv は SEARCH_BUCKET_NAME のバケットを使う Store を返す
+// 未設定の場合はインデックスを更新・検索しないため、その旨をログに残す
+func StoreFromEnv(client *s3.Client) *Store {
+	bucket := os.Getenv("SEARCH_BUCKET_NAME")
+	if bucket == "" {
+		fmt.Fprintln(os.Stderr, "Warning: SEARCH_BUCKET_NAME is not set; the search index will not be read or updated")
+	}
+	return &Store{Client: client, Bucket: bucket}
+}
+
+// cached: S3から読み出したオブジェクトとそのETag
+type cached[T any] struct {
+	value *T
+	etag  string
+}
+
+// Load はインデックスを読み出す (まだ作られていない場合は空のインデックス)
+// 返したインデックスは他の呼び出しと共有するため、変更してはいけない
+func (s *Store) Load(ctx context.Context) (*Index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.loadIndex(ctx)
+}
+
+// LoadSuggestions は入力補完の候補を返す (インデックスがまだ作られていない場合は空)
+// 候補は読み出したインデックスから作り、インデックスが変わるまで使い回す
+// 別のオブジェクトとして書き込まないため、インデックスと食い違うことは無い
+func (s *Store) LoadSuggestions(ctx context.Context) (*Suggestions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.loadIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if s.suggestions.value == nil || s.suggestions.etag != s.index.etag {
+		s.suggestions = cached[Suggestions]{value: BuildSuggestions(idx), etag: s.index.etag}
+	}
+	return s.suggestions.value, nil
+}
+
+func (s *Store) loadIndex(ctx context.Context) (*Index, error) {
+	return load(ctx, s, cmp.Or(s.Key, DefaultKey), &s.index, New)
+}
+
+// load はキャッシュしたETagで条件付きGETを行い、変更されていた場合だけ読み直す
+// オブジェクトが存在しない場合は empty() を返す (キャッシュのETagは空になる)
+func load[T any](ctx context.Context, s *Store, key string, c *cached[T], empty func() *T) (*T, error) {
+	if s.Bucket == "" {
+		return empty(), nil
+	}
+	input := &s3.GetObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(key)}
+	if c.value != nil && c.etag != "" {
+		input.IfNoneMatch = aws.String(c.etag)
+	}
+
+	output, err := s.Client.GetObject(ctx, input)
+	if statusCode(err) == 304 {
+		return c.value, nil
+	}
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		c.value, c.etag = empty(), ""
+		return c.value, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	defer output.Body.Close()
+
+	body, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	value := empty()
+	if err := json.Unmarshal(body, value); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", key, err)
+	}
+	c.value, c.etag = value, aws.ToString(output.ETag)
+	return value, nil
+}
+
+// Update はインデックスを読み出して update で変更し、書き戻す
+// 読み出してから他の呼び出しに更新されていた場合 (条件付き書き込みの失敗) は読み直してやり直す
+func (s *Store) Update(ctx context.Context, update func(*Index)) error {
+	if s == nil || s.Bucket == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		idx, err := s.loadIndex(ctx)
+		if err != nil {
+			return err
+		}
+		etag := s.index.etag
+		update(idx)
+		// 書き込みに失敗した場合に変更途中のインデックスを使わないよう、キャッシュは書き込めた場合だけ残す
+		s.index = cached[Index]{}
+
+		err = s.putIndex(ctx, idx, etag, true)
+		if err == nil {
+			return nil
+		}
+		if !isConditionFailed(err) || attempt == maxUpdateAttempts {
+			return err
+		}
+		fmt.Printf("Search index was modified concurrently, retrying (attempt %d)\n", attempt)
+	}
+}
+
+// Replace はインデックス全体を置き換える (rebuild-search-index で使う)
+func (s *Store) Replace(ctx context.Context, idx *Index) error {
+	if s.Bucket == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.index = cached[Index]{}
+	return s.putIndex(ctx, idx, "", false)
+}
+
+// putIndex はインデックスを書き込む
+// conditional の場合は、読み出した時点から変わっていない (etag が空の場合はまだ存在しない) 場合だけ書き込む
+func (s *Store) putIndex(ctx context.Context, idx *Index, etag string, conditional bool) error {
+	input := &s3.PutObjectInput{Key: aws.String(cmp.Or(s.Key, DefaultKey))}
+	switch {
+	case conditional && etag != "":
+		input.IfMatch = aws.String(etag)
+	case conditional:
+		input.IfNoneMatch = aws.String("*")
+	}
+
+	newETag, err := s.put(ctx, input, idx)
+	if err != nil {
+		return fmt.Errorf("failed to put search index: %w", err)
+	}
+	s.index = cached[Index]{value: idx, etag: newETag}
+	return nil
+}
+
+// put は v をJSONにして書き込み、新しいETagを返す
+func (s *Store) put(ctx context.Context, input *s3.PutObjectInput, v any) (string, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	input.Bucket = aws.String(s.Bucket)
+	input.Body = bytes.NewReader(body)
+	input.ContentType = aws.String("application/json")
+
+	output, err := s.Client.PutObject(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(output.ETag), nil
+}
+
+// isConditionFailed は条件付き書き込みが他の書き込みと競合して失敗したかを返す (412 / 409)
+func isConditionFailed(err error) bool {
+	code := statusCode(err)
+	return code == 412 || code == 409
+}
+
+// statusCode はS3のエラーのHTTPステータスコードを返す (HTTPのエラーでない場合は0)
+func statusCode(err error) int {
+	var status interface{ HTTPStatusCode() int }
+	if !errors.As(err, &status) {
+		return 0
+	}
+	return status.HTTPStatusCode()
+}
@@ -0,0 +1,201 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/posts"
+)
+
+// maxSuggestTerms: 候補に含める語の上限 (出現する記事の数が多い順)
+const maxSuggestTerms = 5000
+
+// 候補の種類
+const (
+	SuggestTitle = "title"
+	SuggestTag   = "tag"
+	SuggestTerm  = "term"
+)
+
+// Suggestions: 検索語の入力補完に使う前方一致の候補 (Key の昇順)
+// 読み出した検索インデックスから作り、インデックスが変わるまで使い回す (Store.LoadSuggestions)
+type Suggestions struct {
+	Entries []Suggestion `json:"entries"`
+}
+
+// Suggestion: 入力補完の候補
+type Suggestion struct {
+	Key      string `json:"key"` // 前方一致に使う正規化したテキスト (SuggestKey)
+	Text     string `json:"text"`
+	Type     string `json:"type"`               // title / tag / term
+	ID       string `json:"id,omitempty"`       // title の場合は記事のID
+	Slug     string `json:"slug,omitempty"`     // title の場合は記事のスラッグ
+	Weight   int    `json:"weight"`             // 並び順に使う重み (タグと語はその記事の数)
+	ExpireAt string `json:"expireAt,omitempty"` // title の場合は記事の公開終了日時 (Match で除外する)
+}
+
+// BuildSuggestions はインデックスに登録された記事のタイトル・タグ・頻出語から候補を作る
+// 候補はインデックスが変わるまで使い回すため、公開終了日時を過ぎたタイトルは Match で除外する
+func BuildSuggestions(idx *Index) *Suggestions {
+	var entries []Suggestion
+	tags := map[string]int{}
+	tagText := map[string]string{}
+	terms := map[string]int{}
+	for _, d := range idx.Docs {
+		entries = append(entries, Suggestion{Key: SuggestKey(d.Title), Text: d.Title, Type: SuggestTitle, ID: d.ID, Slug: d.Slug, Weight: 1, ExpireAt: d.ExpireAt})
+		for _, tag := range d.Tags {
+			key := SuggestKey(tag)
+			tags[key]++
+			tagText[key] = tag
+		}
+		for _, word := range d.Words {
+			terms[word]++
+		}
+	}
+	for key, count := range tags {
+		entries = append(entries, Suggestion{Key: key, Text: tagText[key], Type: SuggestTag, Weight: count})
+	}
+
+	words := make([]string, 0, len(terms))
+	for word := range terms {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if terms[words[i]] != terms[words[j]] {
+			return terms[words[i]] > terms[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	for _, word := range words[:min(len(words), maxSuggestTerms)] {
+		entries = append(entries, Suggestion{Key: SuggestKey(word), Text: word, Type: SuggestTerm, Weight: terms[word]})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Key != entries[j].Key {
+			return entries[i].Key < entries[j].Key
+		}
+		return entries[i].Type < entries[j].Type
+	})
+	return &Suggestions{Entries: entries}
+}
+
+// typeOrder: 重みが同じ場合の並び順 (タグ → タイトル → 語)
+var typeOrder = map[string]int{SuggestTag: 0, SuggestTitle: 1, SuggestTerm: 2}
+
+// Match は prefix で始まる候補を重みの大きい順に最大 limit 件返す
+// 同じテキストの候補が複数の種類にある場合は、先に並ぶもの (タグなど) だけを返す
+// now の時点で公開終了日時を過ぎた記事のタイトルは返さない
+func (s *Suggestions) Match(prefix string, limit int, now time.Time) []Suggestion {
+	key := SuggestKey(prefix)
+	if key == "" {
+		return nil
+	}
+
+	start := sort.Search(len(s.Entries), func(i int) bool { return s.Entries[i].Key >= key })
+	var matches []Suggestion
+	for i := start; i < len(s.Entries) && strings.HasPrefix(s.Entries[i].Key, key); i++ {
+		if posts.Expired(s.Entries[i].ExpireAt, now) {
+			continue
+		}
+		matches = append(matches, s.Entries[i])
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Weight != matches[j].Weight {
+			return matches[i].Weight > matches[j].Weight
+		}
+		if typeOrder[matches[i].Type] != typeOrder[matches[j].Type] {
+			return typeOrder[matches[i].Type] < typeOrder[matches[j].Type]
+		}
+		return matches[i].Key < matches[j].Key
+	})
+
+	result := []Suggestion{}
+	seen := map[string]bool{}
+	for _, m := range matches {
+		if len(result) == limit {
+			break
+		}
+		if m.Type != SuggestTitle && seen[m.Key] {
+			continue
+		}
+		seen[m.Key] = true
+		result = append(result, m)
+	}
+	return result
+}
+
+// SuggestKey は前方一致に使うテキストにする
+// 全角英数字を半角に、英字を小文字に、カタカナをひらがなにして、連続する空白を1つにまとめる
+// (「でー」でも「デー」でも「データベース」に一致する)
+func SuggestKey(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.TrimSpace(text) {
+		r = Fold(r)
+		if r >= 'ァ' && r <= 'ヶ' {
+			r -= 'ァ' - 'ぁ'
+		}
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// vocabulary はテキストから候補にする語を取り出す
+//   - 英数字の単語 (2文字以上、数字だけのものと stopWords を除く)
+//   - カタカナ (長音符を含む) の連続と、漢字の連続 (2文字以上)
+//
+// ひらがなは助詞・送り仮名が多いため語にしない
+func vocabulary(text string) []string {
+	var words []string
+	for _, run := range runs(text) {
+		if !run.cjk {
+			word := string(run.runes)
+			if len(run.runes) >= 2 && !stopWords[word] && strings.IndexFunc(word, unicode.IsLetter) >= 0 {
+				words = append(words, word)
+			}
+			continue
+		}
+
+		var current []rune
+		script := ""
+		flush := func() {
+			if len(current) >= 2 && script != "hiragana" {
+				words = append(words, string(current))
+			}
+			current = nil
+		}
+		for _, r := range run.runes {
+			s := scriptOf(r)
+			if s != script {
+				flush()
+				script = s
+			}
+			current = append(current, r)
+		}
+		flush()
+	}
+	return words
+}
+
+// scriptOf はCJKの文字の種類を返す
+func scriptOf(r rune) string {
+	switch {
+	case unicode.Is(unicode.Katakana, r) || r == 'ー' || r == 'ｰ':
+		return "katakana"
+	case unicode.Is(unicode.Han, r) || r == '々' || r == '〆':
+		return "han"
+	case unicode.Is(unicode.Hiragana, r):
+		return "hiragana"
+	}
+	return "other"
+}
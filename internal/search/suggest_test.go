@@ -0,0 +1,37 @@
+package search
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMatchSkipsExpiredTitles(t *testing.T) {
+	idx := New()
+	idx.Put(Document{ID: "current", Title: "Go testing", Date: "2024-04-01"})
+	idx.Put(Document{ID: "expired", Title: "Go tips", Date: "2024-04-02", ExpireAt: "2024-04-30T00:00:00Z"})
+	suggestions := BuildSuggestions(idx)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want []string
+	}{
+		{"before expiry", time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC), []string{"current", "expired"}},
+		{"after expiry", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), []string{"current"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			for _, m := range suggestions.Match("go t", 10, tt.now) {
+				if m.Type == SuggestTitle {
+					ids = append(ids, m.ID)
+				}
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("Match(go t) titles = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/textstat"
+)
+
+// stopWords: 索引に含めない英語の語
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"that": true, "the": true, "this": true, "to": true, "was": true, "with": true,
+}
+
+// Tokenize はテキストを索引の語に分割する
+//   - CJKの文字の連続はバイグラム (2文字ずつ) にする。1文字だけの場合はその1文字
+//   - それ以外の文字・数字の連続は小文字の単語にして、語幹を取り出す (running → run)
+//   - 全角英数字は半角として扱う
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, run := range runs(text) {
+		if run.cjk {
+			tokens = append(tokens, bigrams(run.runes)...)
+			continue
+		}
+		word := string(run.runes)
+		if stopWords[word] {
+			continue
+		}
+		tokens = append(tokens, Stem(word))
+	}
+	return tokens
+}
+
+// run: 同じ種類 (CJK / それ以外の文字・数字) の文字の連続
+type run struct {
+	runes []rune
+	cjk   bool
+}
+
+// runs はテキストを正規化し、CJKの文字の連続と単語に分ける (記号と空白は区切りとして捨てる)
+// アポストロフィは単語の区切りにせずに取り除く (don't → dont)
+func runs(text string) []run {
+	var result []run
+	var current []rune
+	cjk := false
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, run{runes: current, cjk: cjk})
+		}
+		current = nil
+	}
+
+	for _, r := range text {
+		r = Fold(r)
+		switch {
+		case textstat.IsCJK(r):
+			if !cjk {
+				flush()
+			}
+			cjk = true
+			current = append(current, r)
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if cjk {
+				flush()
+			}
+			cjk = false
+			current = append(current, r)
+		case r == '\'' || r == '’' || unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		default:
+			flush()
+		}
+	}
+	flush()
+	return result
+}
+
+// bigrams はCJKの文字の連続をバイグラムにする
+func bigrams(runes []rune) []string {
+	if len(runes) == 1 {
+		return []string{string(runes)}
+	}
+	tokens := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		tokens = append(tokens, string(runes[i:i+2]))
+	}
+	return tokens
+}
+
+// Fold は文字を検索用に正規化する (全角英数字・記号を半角に、英字を小文字に)
+func Fold(r rune) rune {
+	switch {
+	case r >= '！' && r <= '～':
+		r -= '！' - '!'
+	case r == '　':
+		r = ' '
+	}
+	return unicode.ToLower(r)
+}
+
+// Stem は英単語の語幹を取り出す (Porter stemmer の主要な規則だけを実装した簡易版)
+// 検索語と索引で同じ規則を使うため、実際の語幹と一致しなくてもよい
+func Stem(word string) string {
+	if len(word) <= 3 || !isASCIILetters(word) {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"),
+		strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "zes"):
+		word = strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		word = strings.TrimSuffix(word, "s")
+	}
+
+	for _, suffix := range []string{"ing", "ed", "ly"} {
+		stem, ok := strings.CutSuffix(word, suffix)
+		if !ok || len(stem) < 3 || !hasVowel(stem) {
+			continue
+		}
+		// running → runn → run (子音の重なりを1つにする。ll / ss / zz は残す)
+		if n := len(stem); n >= 2 && stem[n-1] == stem[n-2] && !strings.ContainsRune("aeioulsz", rune(stem[n-1])) {
+			stem = stem[:n-1]
+		}
+		return stem
+	}
+	return word
+}
+
+func isASCIILetters(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'a' || s[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func hasVowel(s string) bool {
+	return strings.ContainsAny(s, "aeiouy")
+}
@@ -0,0 +1,46 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"cjk bigrams", "東京都", []string{"東京", "京都"}},
+		{"single cjk", "猫", []string{"猫"}},
+		{"kana bigrams", "ラーメン", []string{"ラー", "ーメ", "メン"}},
+		{"mixed scripts", "Go言語で書く", []string{"go", "言語", "語で", "で書", "書く"}},
+		{"stop words and stems", "The running dogs", []string{"run", "dog"}},
+		{"full width", "ＧＯＬＡＮＧ", []string{"golang"}},
+		{"apostrophe", "don't", []string{"dont"}},
+		{"punctuation splits cjk", "東京、大阪", []string{"東京", "大阪"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"running": "run",
+		"boxes":   "box",
+		"stories": "story",
+		"quickly": "quick",
+		"class":   "class",
+		"go":      "go",
+	}
+	for word, want := range tests {
+		if got := Stem(word); got != want {
+			t.Errorf("Stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
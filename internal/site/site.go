@@ -0,0 +1,83 @@
+// Package site はフロントエンド (ホームページ) 側の公開URLやサイト情報の設定を環境変数から読み込む
+//
+// ルーティングはフロントエンドが持っているため、記事やタグのURLはパターンとして設定できるようにしている。
+package site
+
+import (
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Config: サイトの設定
+type Config struct {
+	URL            string // SITE_URL (ex. https://example.com)
+	Title          string // SITE_TITLE
+	Description    string // SITE_DESCRIPTION
+	Language       string // SITE_LANGUAGE (既定値 "ja")
+	PostURLPattern string // POST_URL_PATTERN (既定値 "/blog/{id}")。{id} を記事ID、{slug} をスラッグに置き換える
+	TagURLPattern  string // TAG_URL_PATTERN (既定値 "/blog/tags/{tag}")。{tag} をタグ名に置き換える
+
+	// FeedURLPattern / TagFeedURLPattern はフィードの公開URLのパターン。{name} をファイル名 (feed.xml など)、{tag} をタグ名に置き換える
+	FeedURLPattern    string // FEED_URL_PATTERN (既定値 "/{name}")
+	TagFeedURLPattern string // TAG_FEED_URL_PATTERN (既定値 "/tags/{tag}/{name}")
+}
+
+// FromEnv は環境変数から設定を読み込む
+func FromEnv() Config {
+	return Config{
+		URL:            strings.TrimSuffix(os.Getenv("SITE_URL"), "/"),
+		Title:          os.Getenv("SITE_TITLE"),
+		Description:    os.Getenv("SITE_DESCRIPTION"),
+		Language:       getenv("SITE_LANGUAGE", "ja"),
+		PostURLPattern: getenv("POST_URL_PATTERN", "/blog/{id}"),
+		TagURLPattern:  getenv("TAG_URL_PATTERN", "/blog/tags/{tag}"),
+
+		FeedURLPattern:    getenv("FEED_URL_PATTERN", "/{name}"),
+		TagFeedURLPattern: getenv("TAG_FEED_URL_PATTERN", "/tags/{tag}/{name}"),
+	}
+}
+
+// PostURL は記事の公開URLを返す
+// スラッグが未割り当ての記事 (スラッグ導入前に公開されたもの) では {slug} の代わりに記事IDを使う
+func (c Config) PostURL(id, slug string) string {
+	if slug == "" {
+		slug = id
+	}
+	path := strings.ReplaceAll(c.PostURLPattern, "{id}", url.PathEscape(id))
+	path = strings.ReplaceAll(path, "{slug}", url.PathEscape(slug))
+	return c.Absolute(path)
+}
+
+// TagURL はタグ一覧ページの公開URLを返す
+func (c Config) TagURL(tag string) string {
+	return c.Absolute(strings.ReplaceAll(c.TagURLPattern, "{tag}", url.PathEscape(tag)))
+}
+
+// FeedURL はフィードの公開URLを返す (tag が空の場合はサイト全体のフィード)
+// API Gateway のステージやカスタムドメインのマッピングに左右されないよう、リクエストのパスではなく設定から組み立てる
+func (c Config) FeedURL(name, tag string) string {
+	if tag == "" {
+		return c.Absolute(strings.ReplaceAll(c.FeedURLPattern, "{name}", name))
+	}
+	path := strings.ReplaceAll(c.TagFeedURLPattern, "{tag}", url.PathEscape(tag))
+	return c.Absolute(strings.ReplaceAll(path, "{name}", name))
+}
+
+// Absolute はサイト内のパスを絶対URLにする (既に絶対URLの場合はそのまま返す)
+func (c Config) Absolute(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return c.URL + path
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
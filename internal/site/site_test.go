@@ -0,0 +1,20 @@
+package site
+
+import "testing"
+
+func TestFeedURL(t *testing.T) {
+	c := Config{URL: "https://example.com", FeedURLPattern: "/{name}", TagFeedURLPattern: "/tags/{tag}/{name}"}
+	tests := []struct {
+		name, tag, want string
+	}{
+		{"feed.xml", "", "https://example.com/feed.xml"},
+		{"atom.xml", "", "https://example.com/atom.xml"},
+		{"feed.json", "go", "https://example.com/tags/go/feed.json"},
+		{"feed.xml", "日記 2024", "https://example.com/tags/%E6%97%A5%E8%A8%98%202024/feed.xml"},
+	}
+	for _, tt := range tests {
+		if got := c.FeedURL(tt.name, tt.tag); got != tt.want {
+			t.Errorf("FeedURL(%q, %q) = %q, want %q", tt.name, tt.tag, got, tt.want)
+		}
+	}
+}
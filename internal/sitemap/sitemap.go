@@ -0,0 +1,66 @@
+// Package sitemap は Sitemaps プロトコル (https://www.sitemaps.org/protocol.html) の sitemap / sitemap index を生成する
+package sitemap
+
+import (
+	"encoding/xml"
+)
+
+// MaxURLs: 1つのsitemapに含められるURLの上限 (プロトコルの制限)
+const MaxURLs = 50000
+
+const xmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// URL: sitemapの1エントリー
+type URL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"` // W3C Datetime (ex. 2025-08-26T12:00:00Z)
+}
+
+// Ref: sitemap index から参照する子sitemap
+type Ref struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	URLs    []URL    `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	XMLNS    string   `xml:"xmlns,attr"`
+	Sitemaps []Ref    `xml:"sitemap"`
+}
+
+// URLSet は <urlset> を出力する
+func URLSet(urls []URL) ([]byte, error) {
+	return marshal(urlSet{XMLNS: xmlns, URLs: urls})
+}
+
+// Index は <sitemapindex> を出力する
+func Index(refs []Ref) ([]byte, error) {
+	return marshal(sitemapIndex{XMLNS: xmlns, Sitemaps: refs})
+}
+
+// Pages はURLの一覧をperPage件ずつに分割する
+func Pages(urls []URL, perPage int) [][]URL {
+	if perPage <= 0 || perPage > MaxURLs {
+		perPage = MaxURLs
+	}
+
+	var pages [][]URL
+	for start := 0; start < len(urls); start += perPage {
+		pages = append(pages, urls[start:min(start+perPage, len(urls))])
+	}
+	return pages
+}
+
+func marshal(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
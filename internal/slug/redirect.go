@@ -0,0 +1,99 @@
+package slug
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrCurrentSlug: 記事の現在のスラッグをリダイレクトとして上書き・削除しようとした
+var ErrCurrentSlug = errors.New("slug is the current slug of a post")
+
+// ErrNotRedirect: 指定されたスラッグのリダイレクトが存在しない
+var ErrNotRedirect = errors.New("redirect not found")
+
+// ListRedirects はリダイレクト (以前のスラッグと手動登録) をスラッグ順に返す
+func ListRedirects(ctx context.Context, client *dynamodb.Client, tableName string) ([]Entry, error) {
+	var entries []Entry
+
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:        aws.String(tableName),
+		FilterExpression: aws.String("kind IN (:previous, :manual)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":previous": &types.AttributeValueMemberS{Value: string(KindPrevious)},
+			":manual":   &types.AttributeValueMemberS{Value: string(KindManual)},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var items []Entry
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, err
+		}
+		entries = append(entries, items...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Slug < entries[j].Slug })
+	return entries, nil
+}
+
+// PutRedirect は手動リダイレクトを登録・更新する
+// 転送先は記事 (postId) かサイト内のパス・URL (target) のどちらか
+// 記事の現在のスラッグは上書きできない (ErrCurrentSlug)
+func PutRedirect(ctx context.Context, client *dynamodb.Client, tableName string, entry Entry) (Entry, error) {
+	entry.Kind = KindManual
+	entry.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	av, err := attributevalue.MarshalMap(entry)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(tableName),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(slug) OR kind IN (:previous, :manual)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":previous": &types.AttributeValueMemberS{Value: string(KindPrevious)},
+			":manual":   &types.AttributeValueMemberS{Value: string(KindManual)},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return Entry{}, ErrCurrentSlug
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// DeleteRedirect はリダイレクトを削除する
+// 記事の現在のスラッグは削除できない (ErrNotRedirect)
+func DeleteRedirect(ctx context.Context, client *dynamodb.Client, tableName, slug string) error {
+	_, err := client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"slug": &types.AttributeValueMemberS{Value: slug},
+		},
+		ConditionExpression: aws.String("kind IN (:previous, :manual)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":previous": &types.AttributeValueMemberS{Value: string(KindPrevious)},
+			":manual":   &types.AttributeValueMemberS{Value: string(KindManual)},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrNotRedirect
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package slug
+
+import "strings"
+
+// ひらがな1文字 (または拗音などの2文字) からヘボン式ローマ字への対応表
+// カタカナはひらがなに変換してから引く
+var kanaTable = map[string]string{
+	"あ": "a", "い": "i", "う": "u", "え": "e", "お": "o",
+	"か": "ka", "き": "ki", "く": "ku", "け": "ke", "こ": "ko",
+	"さ": "sa", "し": "shi", "す": "su", "せ": "se", "そ": "so",
+	"た": "ta", "ち": "chi", "つ": "tsu", "て": "te", "と": "to",
+	"な": "na", "に": "ni", "ぬ": "nu", "ね": "ne", "の": "no",
+	"は": "ha", "ひ": "hi", "ふ": "fu", "へ": "he", "ほ": "ho",
+	"ま": "ma", "み": "mi", "む": "mu", "め": "me", "も": "mo",
+	"や": "ya", "ゆ": "yu", "よ": "yo",
+	"ら": "ra", "り": "ri", "る": "ru", "れ": "re", "ろ": "ro",
+	"わ": "wa", "ゐ": "i", "ゑ": "e", "を": "o", "ん": "n",
+	"が": "ga", "ぎ": "gi", "ぐ": "gu", "げ": "ge", "ご": "go",
+	"ざ": "za", "じ": "ji", "ず": "zu", "ぜ": "ze", "ぞ": "zo",
+	"だ": "da", "ぢ": "ji", "づ": "zu", "で": "de", "ど": "do",
+	"ば": "ba", "び": "bi", "ぶ": "bu", "べ": "be", "ぼ": "bo",
+	"ぱ": "pa", "ぴ": "pi", "ぷ": "pu", "ぺ": "pe", "ぽ": "po",
+	"ゔ": "vu",
+	"ぁ": "a", "ぃ": "i", "ぅ": "u", "ぇ": "e", "ぉ": "o",
+	"ゃ": "ya", "ゅ": "yu", "ょ": "yo", "ゎ": "wa",
+
+	"きゃ": "kya", "きゅ": "kyu", "きょ": "kyo",
+	"しゃ": "sha", "しゅ": "shu", "しょ": "sho", "しぇ": "she",
+	"ちゃ": "cha", "ちゅ": "chu", "ちょ": "cho", "ちぇ": "che",
+	"にゃ": "nya", "にゅ": "nyu", "にょ": "nyo",
+	"ひゃ": "hya", "ひゅ": "hyu", "ひょ": "hyo",
+	"みゃ": "mya", "みゅ": "myu", "みょ": "myo",
+	"りゃ": "rya", "りゅ": "ryu", "りょ": "ryo",
+	"ぎゃ": "gya", "ぎゅ": "gyu", "ぎょ": "gyo",
+	"じゃ": "ja", "じゅ": "ju", "じょ": "jo", "じぇ": "je",
+	"びゃ": "bya", "びゅ": "byu", "びょ": "byo",
+	"ぴゃ": "pya", "ぴゅ": "pyu", "ぴょ": "pyo",
+
+	// 外来語の表記 (主にカタカナ)
+	"うぃ": "wi", "うぇ": "we", "うぉ": "wo",
+	"ゔぁ": "va", "ゔぃ": "vi", "ゔぇ": "ve", "ゔぉ": "vo",
+	"ふぁ": "fa", "ふぃ": "fi", "ふぇ": "fe", "ふぉ": "fo",
+	"てぃ": "ti", "でぃ": "di", "とぅ": "tu", "どぅ": "du",
+	"でゅ": "dyu", "てゅ": "tyu", "ふゅ": "fyu",
+	"つぁ": "tsa", "つぃ": "tsi", "つぇ": "tse", "つぉ": "tso",
+}
+
+// toHiragana はカタカナをひらがなに変換する (対応するひらがなが無い文字はそのまま)
+func toHiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - ('ァ' - 'ぁ')
+	}
+	return r
+}
+
+// isKatakana はカタカナ (長音符を含む) かを返す
+func isKatakana(r rune) bool {
+	return r == 'ー' || r != toHiragana(r)
+}
+
+func isKana(r rune) bool {
+	r = toHiragana(r)
+	return (r >= 'ぁ' && r <= 'ゖ') || r == 'ー' || r == 'っ'
+}
+
+// transliterateKana は連続したかな文字列をローマ字に変換する
+// 変換できない文字を含む場合は ok=false を返す
+func transliterateKana(kana []rune) (romaji string, ok bool) {
+	var b strings.Builder
+	geminate := false // 直前が促音 (っ)
+
+	for i := 0; i < len(kana); {
+		r := toHiragana(kana[i])
+
+		switch r {
+		case 'っ':
+			geminate = true
+			i++
+			continue
+		case 'ー':
+			// 長音は表記しない (ex. ラーメン -> ramen)
+			i++
+			continue
+		}
+
+		var syllable string
+		if i+1 < len(kana) {
+			if s, found := kanaTable[string([]rune{r, toHiragana(kana[i+1])})]; found {
+				syllable = s
+				i += 2
+			}
+		}
+		if syllable == "" {
+			s, found := kanaTable[string(r)]
+			if !found {
+				return "", false
+			}
+			syllable = s
+			i++
+		}
+
+		if geminate {
+			// 促音は次の子音を重ねる (ex. きって -> kitte, まっちゃ -> matcha)
+			if strings.HasPrefix(syllable, "ch") {
+				b.WriteByte('t')
+			} else if c := syllable[0]; !strings.ContainsRune("aiueon", rune(c)) {
+				b.WriteByte(c)
+			}
+			geminate = false
+		}
+		b.WriteString(syllable)
+	}
+
+	return b.String(), true
+}
@@ -0,0 +1,55 @@
+package slug
+
+import "testing"
+
+func TestTransliterateKana(t *testing.T) {
+	tests := []struct {
+		kana string
+		want string
+	}{
+		{"さくら", "sakura"},
+		{"しんぶん", "shinbun"},
+		{"ちず", "chizu"},
+		{"つくえ", "tsukue"},
+		{"ふじ", "fuji"},
+		{"きって", "kitte"},
+		{"まっちゃ", "matcha"},
+		{"きょうと", "kyouto"},
+		{"じゃがいも", "jagaimo"},
+		{"ラーメン", "ramen"},
+		{"コーヒー", "kohi"},
+		{"シェア", "shea"},
+		{"ヴァイオリン", "vaiorin"},
+		{"パーティー", "pati"},
+		{"フォルダ", "foruda"},
+		{"ウェブ", "webu"},
+		{"ディスク", "disuku"},
+		{"ッ", ""},
+	}
+	for _, tt := range tests {
+		got, ok := transliterateKana([]rune(tt.kana))
+		if !ok || got != tt.want {
+			t.Errorf("transliterateKana(%q) = %q, %v; want %q, true", tt.kana, got, ok, tt.want)
+		}
+	}
+}
+
+func TestTransliterateKanaUnknown(t *testing.T) {
+	// 小書きの「ゕ」は対応表に無い
+	if got, ok := transliterateKana([]rune("ゕ")); ok {
+		t.Errorf("transliterateKana(ゕ) = %q, true; want false", got)
+	}
+}
+
+func TestKanaTableIsHepburn(t *testing.T) {
+	for kana, romaji := range kanaTable {
+		if romaji == "" {
+			t.Errorf("kanaTable[%q] is empty", kana)
+		}
+		for _, r := range romaji {
+			if r < 'a' || r > 'z' {
+				t.Errorf("kanaTable[%q] = %q contains a non-lowercase letter", kana, romaji)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package slug は記事の公開URLに使う人間が読めるスラッグの生成・検証と、スラッグの一意性を保証するインデックスを扱う
+package slug
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength: スラッグの最大長
+const MaxLength = 80
+
+// ShortIDLength: タイトルから生成できない場合に使う短縮IDの長さ
+const ShortIDLength = 8
+
+var ErrInvalid = errors.New("slug must consist of lowercase letters, digits and hyphens")
+
+var validPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// Generate はタイトルからスラッグを生成する
+// 英数字はそのまま小文字に、かなはヘボン式ローマ字に変換する
+// 漢字などローマ字に変換できない文字は記号と同じく単語の区切りとして読み飛ばし、残った部分からスラッグを作る
+// (ex. ラーメンの作り方 -> ramen)。何も残らない場合は記事IDから作った短縮IDを返す
+func Generate(title, postID string) string {
+	var words []string
+	var word strings.Builder
+	var kana []rune
+
+	flushKana := func() {
+		if len(kana) == 0 {
+			return
+		}
+		// ひらがな1文字だけの語 (漢字の間の助詞・送り仮名) はスラッグの意味を持たないので捨てる
+		if len(kana) > 1 || isKatakana(kana[0]) {
+			if romaji, ok := transliterateKana(kana); ok && romaji != "" {
+				words = append(words, romaji)
+			}
+		}
+		kana = kana[:0]
+	}
+	flushWord := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range norm.NFKC.String(title) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			flushKana()
+			word.WriteRune(unicode.ToLower(r))
+		case isKana(r):
+			flushWord()
+			// カタカナとひらがなの境目も単語の区切りにする (ex. ラーメンの -> ramen + no)
+			if n := len(kana); n > 0 && r != 'ー' && isKatakana(r) != isKatakana(kana[n-1]) {
+				flushKana()
+			}
+			kana = append(kana, r)
+		default:
+			// 空白・記号・漢字などは単語の区切りとして扱う
+			flushKana()
+			flushWord()
+		}
+	}
+	flushKana()
+	flushWord()
+
+	slug := truncate(strings.Join(words, "-"))
+	if slug == "" {
+		return ShortID(postID)
+	}
+	return slug
+}
+
+// Key はスラッグのインデックスのキーに使う形に正規化する
+// NFKC (全角英数字を半角に) と小文字化を行い、前後の空白とスラッシュを取り除く
+// 書き込み (Normalize・手動リダイレクトの登録) と読み出し (Lookup) の両方で使う
+func Key(s string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(norm.NFKC.String(s)), "/"))
+}
+
+// Normalize は指定されたスラッグを正規化 (Key) して検証する
+func Normalize(s string) (string, error) {
+	s = Key(s)
+	if len(s) > MaxLength || !validPattern.MatchString(s) {
+		return "", ErrInvalid
+	}
+	return s, nil
+}
+
+// ShortID は記事IDの先頭から短縮IDを作る (UUIDのハイフンは取り除く)
+func ShortID(postID string) string {
+	id := strings.ToLower(strings.ReplaceAll(postID, "-", ""))
+	if len(id) > ShortIDLength {
+		id = id[:ShortIDLength]
+	}
+	return id
+}
+
+// truncate はMaxLengthを超えないように単語の区切りで切り詰める
+func truncate(s string) string {
+	if len(s) <= MaxLength {
+		return s
+	}
+	s = s[:MaxLength]
+	if i := strings.LastIndexByte(s, '-'); i > 0 {
+		s = s[:i]
+	}
+	return strings.Trim(s, "-")
+}
@@ -0,0 +1,80 @@
+package slug
+
+import "testing"
+
+const postID = "5f0c8a2e-4d7b-4f7e-9a51-2f6b8c1d3e90"
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Hello, World!", "hello-world"},
+		{"Ｇｏ１．２４ の新機能", "go1-24"},
+		{"ラーメンの作り方", "ramen"},
+		{"Goで全文検索を実装する", "go-suru"},
+		{"Docker コンテナ入門", "docker-kontena"},
+		{"きょうのできごと", "kyounodekigoto"},
+		{"東京の天気", "5f0c8a2e"},
+		{"!!!", "5f0c8a2e"},
+		{"", "5f0c8a2e"},
+	}
+	for _, tt := range tests {
+		if got := Generate(tt.title, postID); got != tt.want {
+			t.Errorf("Generate(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateTruncates(t *testing.T) {
+	title := ""
+	for i := 0; i < 20; i++ {
+		title += "long title "
+	}
+	got := Generate(title, postID)
+	if len(got) > MaxLength || got[len(got)-1] == '-' {
+		t.Errorf("Generate(long) = %q (len %d)", got, len(got))
+	}
+	if _, err := Normalize(got); err != nil {
+		t.Errorf("Generate(long) = %q is not a valid slug: %v", got, err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"my-post", "my-post"},
+		{"My-Post", "my-post"},
+		{" /my-post/ ", "my-post"},
+		{"ｍｙ－ｐｏｓｔ", "my-post"},
+		{"/2019/Old-Blog/", "2019/old-blog"},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.in); got != tt.want {
+			t.Errorf("Key(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"Hello-World", "hello-world", false},
+		{"ｈｅｌｌｏ", "hello", false},
+		{"hello--world", "", true},
+		{"-hello", "", true},
+		{"hello_world", "", true},
+		{"ラーメン", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q, err=%v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
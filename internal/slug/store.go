@@ -0,0 +1,137 @@
+package slug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxSuffix: 生成したスラッグが重複した場合に "-2", "-3", ... と試す上限
+const maxSuffix = 50
+
+var ErrTaken = errors.New("slug is already used by another post")
+
+// Kind: スラッグのインデックスのアイテムの種類
+type Kind string
+
+const (
+	KindCurrent  Kind = "current"  // 記事の現在のスラッグ
+	KindPrevious Kind = "previous" // 記事の以前のスラッグ (現在のスラッグへリダイレクトする)
+	KindManual   Kind = "manual"   // 管理画面から登録したリダイレクト (旧ブログのURLなど)
+)
+
+// Entry: スラッグのインデックス (SLUGS_TABLE_NAME、主キーは slug) のアイテム
+type Entry struct {
+	Slug      string `json:"slug" dynamodbav:"slug"`
+	Kind      Kind   `json:"kind" dynamodbav:"kind"`
+	PostID    string `json:"postId,omitempty" dynamodbav:"postId,omitempty"` // リダイレクト先の記事
+	Target    string `json:"target,omitempty" dynamodbav:"target,omitempty"` // 手動リダイレクトの転送先 (サイト内のパスまたはURL)
+	UpdatedAt string `json:"updatedAt,omitempty" dynamodbav:"updatedAt,omitempty"`
+}
+
+// IsRedirect はアイテムがリダイレクト (以前のスラッグまたは手動登録) かを返す
+func (e Entry) IsRedirect() bool {
+	return e.Kind == KindPrevious || e.Kind == KindManual
+}
+
+// Put はスラッグを記事の現在のスラッグとして割り当てる書き込みを返す
+// 記事の保存と同じトランザクションに含めて、記事の保存に失敗した場合にスラッグだけが割り当てられたままにならないようにする
+// 他の記事や手動リダイレクトが既に使っている場合は条件式で失敗する
+// (同じ記事の以前のスラッグを再び使う場合は成功する)
+func Put(tableName, slug, postID string, now time.Time) (types.TransactWriteItem, error) {
+	av, err := attributevalue.MarshalMap(Entry{
+		Slug:      slug,
+		Kind:      KindCurrent,
+		PostID:    postID,
+		UpdatedAt: now.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+	return types.TransactWriteItem{Put: &types.Put{
+		TableName:           aws.String(tableName),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(slug) OR postId = :postId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":postId": &types.AttributeValueMemberS{Value: postID},
+		},
+	}}, nil
+}
+
+// Retire は記事の以前のスラッグ from を現在のスラッグへのリダイレクトに変える書き込みを返す
+// Put と同じトランザクションに含める。from を記事が使っていない場合は条件式で失敗する
+func Retire(tableName, from, postID string, now time.Time) types.TransactWriteItem {
+	return types.TransactWriteItem{Update: &types.Update{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"slug": &types.AttributeValueMemberS{Value: from},
+		},
+		UpdateExpression:    aws.String("SET kind = :kind, updatedAt = :updatedAt"),
+		ConditionExpression: aws.String("postId = :postId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":kind":      &types.AttributeValueMemberS{Value: string(KindPrevious)},
+			":updatedAt": &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+			":postId":    &types.AttributeValueMemberS{Value: postID},
+		},
+	}}
+}
+
+// Owned はスラッグを記事が使っているか (現在のスラッグまたは以前のスラッグ) を返す
+func Owned(ctx context.Context, client *dynamodb.Client, tableName, slug, postID string) (bool, error) {
+	entry, err := get(ctx, client, tableName, slug)
+	if err != nil {
+		return false, err
+	}
+	return entry != nil && entry.PostID == postID, nil
+}
+
+// Unique は base から始めて、他の記事と重複しないスラッグを探して返す
+// 割り当ては Put で行う (探してから割り当てるまでに他の記事が使った場合は、Put の条件式で失敗する)
+func Unique(ctx context.Context, client *dynamodb.Client, tableName, base, postID string) (string, error) {
+	candidate := base
+	for i := 2; i <= maxSuffix; i++ {
+		entry, err := get(ctx, client, tableName, candidate)
+		if err != nil {
+			return "", err
+		}
+		if entry == nil || entry.PostID == postID {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	// 重複が多すぎる場合は短縮IDを付けて一意にする
+	return fmt.Sprintf("%s-%s", base, ShortID(postID)), nil
+}
+
+// Lookup はスラッグのインデックスを書き込み時と同じく Key で正規化して引く
+func Lookup(ctx context.Context, client *dynamodb.Client, tableName, slug string) (*Entry, error) {
+	return get(ctx, client, tableName, Key(slug))
+}
+
+func get(ctx context.Context, client *dynamodb.Client, tableName, slug string) (*Entry, error) {
+	result, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"slug": &types.AttributeValueMemberS{Value: slug},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var entry Entry
+	if err := attributevalue.UnmarshalMap(result.Item, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
@@ -0,0 +1,128 @@
+// Package textstat は日本語と英語が混在する本文の文字数・単語数・読了時間・要約を計算する
+//
+// 日本語は空白で単語を区切らないため、CJKの文字は1文字ずつ、それ以外の文字 (ラテン文字など) は
+// 単語単位で数え、それぞれの読む速さから読了時間を求める。
+package textstat
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+
+	"github.com/sunshine-724/my-homepage-backend/internal/markdown"
+)
+
+const (
+	CharsPerMinute = 500 // CJKの文字を1分間に読む文字数
+	WordsPerMinute = 200 // ラテン文字などの単語を1分間に読む単語数
+	ExcerptLength  = 120 // 要約の最大文字数 (書記素クラスタ単位)
+)
+
+// Stats: 本文の統計
+type Stats struct {
+	CharCount   int    // CJKの文字数 (句読点・記号を除く)
+	WordCount   int    // CJK以外の単語数
+	ReadingTime int    // 読了時間の目安 (分、本文が空の場合は0)
+	Excerpt     string // Markdownの記法を取り除いたプレーンテキストの要約
+}
+
+// Analyze は Markdown の本文の統計を計算する
+// コードブロックは要約には含めないが、文字数・単語数と読了時間には含める
+func Analyze(source string) Stats {
+	prose, code := markdown.PlainText(source)
+
+	var stats Stats
+	for _, s := range []string{prose, code} {
+		chars, words := Count(s)
+		stats.CharCount += chars
+		stats.WordCount += words
+	}
+	stats.ReadingTime = ReadingTime(stats.CharCount, stats.WordCount)
+	stats.Excerpt = Truncate(Normalize(prose), ExcerptLength)
+	return stats
+}
+
+// Count はCJKの文字数と、それ以外の単語数を数える
+// 単語は文字・数字の連続とし、単語の途中のアポストロフィとハイフン (don't, well-known) では区切らない
+func Count(s string) (chars, words int) {
+	inWord := false
+	var prev rune
+	for _, r := range s {
+		switch {
+		case IsCJK(r):
+			chars++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if !inWord {
+				words++
+			}
+			inWord = true
+		case inWord && (r == '\'' || r == '’' || r == '-') && (unicode.IsLetter(prev) || unicode.IsNumber(prev)):
+			// 次の文字が文字・数字であれば同じ単語として続ける
+		case unicode.In(r, unicode.Mn, unicode.Mc, unicode.Me):
+			// 結合文字は直前の文字の一部として扱う
+		default:
+			inWord = false
+		}
+		prev = r
+	}
+	return chars, words
+}
+
+// ReadingTime は文字数と単語数から読了時間 (分、切り上げ) を求める
+func ReadingTime(chars, words int) int {
+	if chars == 0 && words == 0 {
+		return 0
+	}
+	minutes := float64(chars)/CharsPerMinute + float64(words)/WordsPerMinute
+	return max(1, int(math.Ceil(minutes)))
+}
+
+// IsCJK は漢字・ひらがな・カタカナ・ハングル (長音符・繰り返し記号を含む) かを返す
+func IsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		r == 'ー' || r == '々' || r == '〆' || r == 'ｰ'
+}
+
+// Normalize は連続する空白を1つにまとめる
+// 1つの改行を挟んで両側がCJKの文字の場合は、空白を入れずにつなげる (日本語の段落内の改行)
+func Normalize(s string) string {
+	var b strings.Builder
+	var last rune
+	pending, newlines := false, 0
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			pending = true
+			if r == '\n' {
+				newlines++
+			}
+			continue
+		}
+		if pending && b.Len() > 0 && !(newlines == 1 && IsCJK(last) && IsCJK(r)) {
+			b.WriteByte(' ')
+		}
+		pending, newlines = false, 0
+		b.WriteRune(r)
+		last = r
+	}
+	return b.String()
+}
+
+// Truncate は s を maxGraphemes 個の書記素クラスタ以内に切り詰める (切り詰めた場合は末尾に … を付ける)
+// 結合文字・異体字セレクタ・ZWJで結合した絵文字・国旗・ハングルの字母などの途中では切らない (UAX #29)
+func Truncate(s string, maxGraphemes int) string {
+	count := 0
+	state := -1
+	for rest, offset := s, 0; rest != ""; {
+		var cluster string
+		cluster, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		if count == maxGraphemes {
+			return strings.TrimSpace(s[:offset]) + "…"
+		}
+		count++
+		offset += len(cluster)
+	}
+	return s
+}
@@ -0,0 +1,35 @@
+package textstat
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{"short", "abc", 3, "abc"},
+		{"ascii", "abcdef", 3, "abc…"},
+		{"trims trailing space", "ab cdef", 3, "ab…"},
+		{"japanese", "日本語の文章", 3, "日本語…"},
+		{"combining mark", "ééé", 2, "éé…"},
+		{"combining dakuten", "か\u3099か\u3099", 1, "か\u3099…"},
+		{"variation selector", "❤️❤️", 1, "❤️…"},
+		{"flags", "🇯🇵🇺🇸🇫🇷", 2, "🇯🇵🇺🇸…"},
+		{"odd regional indicators", "🇯🇵🇺", 1, "🇯🇵…"},
+		{"zwj family", "👨‍👩‍👧‍👦x", 1, "👨‍👩‍👧‍👦…"},
+		{"skin tone", "👍🏽👍🏽", 1, "👍🏽…"},
+		{"tag sequence flag", "🏴\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007Fx", 1, "🏴\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007F…"},
+		{"hangul jamo", "\u1100\u1161\u11a8\u1100\u1161", 1, "\u1100\u1161\u11a8…"},
+		{"crlf", "a\r\nb", 2, "a…"},
+		{"zero", "abc", 0, "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Truncate(tt.s, tt.max); got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Package timestamp はサーバーが管理する日時 (createdAt / updatedAt / publishedAt) と記事の表示用の日付を扱う
+//
+// 保存する日時は常にUTCのRFC 3339とする。
+// 表示用の日付 (date) は著者が入力する自由形式の文字列だが、省略された場合は
+// SITE_TIMEZONE (既定値 Asia/Tokyo) での日付を使う。
+package timestamp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimezone: SITE_TIMEZONE が未設定の場合のタイムゾーン
+const DefaultTimezone = "Asia/Tokyo"
+
+// jst はタイムゾーンのデータベースが使えない環境 (provided.al2 など) での Asia/Tokyo の代わり
+var jst = time.FixedZone("JST", 9*60*60)
+
+// Format は日時をUTCのRFC 3339にする
+func Format(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Location は表示用の日付に使うタイムゾーンを返す (SITE_TIMEZONE)
+var Location = sync.OnceValue(func() *time.Location {
+	name := os.Getenv("SITE_TIMEZONE")
+	if name == "" {
+		name = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		if name != DefaultTimezone {
+			fmt.Printf("Unknown SITE_TIMEZONE %q, falling back to %s: %v\n", name, DefaultTimezone, err)
+		}
+		return jst
+	}
+	return loc
+})
+
+// DisplayDate は日時を表示用のタイムゾーンでの日付 (YYYY-MM-DD) にする
+func DisplayDate(t time.Time) string {
+	return t.In(Location()).Format(time.DateOnly)
+}
+
+// Date は著者が入力した表示用の日付を整える
+//   - 空の場合は now の日付 (表示用のタイムゾーン)
+//   - RFC 3339 の日時の場合は、表示用のタイムゾーンでの日付
+//   - それ以外 (YYYY-MM-DD など) はそのまま
+func Date(date string, now time.Time) string {
+	date = strings.TrimSpace(date)
+	if date == "" {
+		return DisplayDate(now)
+	}
+	if t, err := time.Parse(time.RFC3339, date); err == nil {
+		return DisplayDate(t)
+	}
+	return date
+}
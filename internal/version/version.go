@@ -0,0 +1,177 @@
+// Package version は下書き・記事の version 属性による楽観的排他制御を扱う
+//
+// 読み出しでは version を ETag ("3" の形式) として返し、書き込みでは If-Match で受け取った version と
+// テーブル上の version が一致する場合だけ ConditionExpression で書き込む。
+// version 属性の無い既存のアイテムは version 0 として扱う。
+package version
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var (
+	// ErrMissing は書き込みのリクエストに If-Match ヘッダーが無い場合のエラー (428)
+	ErrMissing = errors.New("If-Match header is required")
+	// ErrInvalid は If-Match ヘッダーを version として解釈できない場合のエラー (400)
+	ErrInvalid = errors.New("invalid If-Match header")
+)
+
+// Any は If-Match: * (現在のversionを問わない) を表す
+const Any = -1
+
+// MismatchError は If-Match の version がテーブル上の version と一致しない場合のエラー (412)
+type MismatchError struct {
+	Current int // テーブル上の現在のversion
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("version mismatch: current version is %d", e.Current)
+}
+
+// ETag は version を ETag ヘッダーの値にする
+func ETag(v int) string {
+	return strconv.Quote(strconv.Itoa(v))
+}
+
+// ETagWith は version に、version を進めずに変わる部分 (記事の前後のナビゲーションなど) のハッシュを加えた
+// ETag ヘッダーの値にする ("3-1a2b3c4d" の形式)。If-Match では "-" 以降を無視する
+func ETagWith(v int, extra []byte) string {
+	sum := sha256.Sum256(extra)
+	return strconv.Quote(strconv.Itoa(v) + "-" + hex.EncodeToString(sum[:4]))
+}
+
+// IfMatch はリクエストの If-Match ヘッダーから version を取り出す
+// "3"・W/"3"・3・"3-1a2b3c4d" (ETagWith) の形式を受け付け、* の場合は Any を返す
+func IfMatch(request events.APIGatewayProxyRequest) (int, error) {
+	var value string
+	for key, v := range request.Headers {
+		if strings.EqualFold(key, "If-Match") {
+			value = strings.TrimSpace(v)
+			break
+		}
+	}
+	if value == "" {
+		return 0, ErrMissing
+	}
+	if value == "*" {
+		return Any, nil
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	value = strings.Trim(value, `"`)
+	if i := strings.IndexByte(value, '-'); i > 0 {
+		value = value[:i]
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil || v < 0 {
+		return 0, ErrInvalid
+	}
+	return v, nil
+}
+
+// Check は If-Match の version (expected) と読み出したアイテムの version を比べる
+func Check(expected, current int) error {
+	if expected != Any && expected != current {
+		return &MismatchError{Current: current}
+	}
+	return nil
+}
+
+// Condition は読み出した時点の version (current) から変わっていない場合だけ書き込む条件式を返す
+// 式の中では #version / :expectedVersion を使うので、Names と Values を書き込みの入力に加えること
+func Condition(current int) (expression string, names map[string]string, values map[string]types.AttributeValue) {
+	names = map[string]string{"#version": "version"}
+	values = map[string]types.AttributeValue{
+		":expectedVersion": &types.AttributeValueMemberN{Value: strconv.Itoa(current)},
+	}
+	if current == 0 {
+		return "(attribute_not_exists(#version) OR #version = :expectedVersion)", names, values
+	}
+	return "#version = :expectedVersion", names, values
+}
+
+// Current はテーブル上のアイテムの現在の version を返す (アイテムが無い場合は found が false)
+func Current(ctx context.Context, client *dynamodb.Client, tableName, id string) (v int, found bool, err error) {
+	result, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ProjectionExpression:     aws.String("#version"),
+		ExpressionAttributeNames: map[string]string{"#version": "version"},
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if result.Item == nil {
+		return 0, false, nil
+	}
+	if n, ok := result.Item["version"].(*types.AttributeValueMemberN); ok {
+		v, _ = strconv.Atoi(n.Value)
+	}
+	return v, true, nil
+}
+
+// ErrorResponse は If-Match に関するエラーをレスポンスにする (該当しない場合は ok が false)
+//   - ErrMissing: 428 Precondition Required
+//   - ErrInvalid: 400 Bad Request
+//   - MismatchError: 412 Precondition Failed (現在のversionをETagヘッダーとボディで返す)
+func ErrorResponse(err error) (response events.APIGatewayProxyResponse, ok bool) {
+	var mismatch *MismatchError
+	switch {
+	case errors.Is(err, ErrMissing):
+		return events.APIGatewayProxyResponse{StatusCode: 428, Body: ErrMissing.Error()}, true
+	case errors.Is(err, ErrInvalid):
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: ErrInvalid.Error()}, true
+	case errors.As(err, &mismatch):
+		body, _ := json.Marshal(map[string]any{
+			"message": "The resource has been modified",
+			"version": mismatch.Current,
+		})
+		return events.APIGatewayProxyResponse{
+			StatusCode: 412,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+				"ETag":         ETag(mismatch.Current),
+			},
+			Body: string(body),
+		}, true
+	}
+	return events.APIGatewayProxyResponse{}, false
+}
+
+// ConditionFailed は書き込みが条件式によって失敗したかを返す
+// TransactWriteItems の場合は index 番目の操作の条件式が失敗したかを見る
+func ConditionFailed(err error, index int) bool {
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return true
+	}
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) && index < len(canceled.CancellationReasons) {
+		return aws.ToString(canceled.CancellationReasons[index].Code) == "ConditionalCheckFailed"
+	}
+	return false
+}
+
+// Conflict は条件付き書き込みが失敗した後に現在の version を読み直して MismatchError を返す
+// アイテムが削除されていた場合は nil を返す
+func Conflict(ctx context.Context, client *dynamodb.Client, tableName, id string) (*MismatchError, error) {
+	current, found, err := Current(ctx, client, tableName, id)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &MismatchError{Current: current}, nil
+}
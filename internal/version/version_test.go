@@ -0,0 +1,153 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+		wantErr error
+	}{
+		{"quoted", map[string]string{"If-Match": `"3"`}, 3, nil},
+		{"header name is case-insensitive", map[string]string{"if-match": `"3"`}, 3, nil},
+		{"weak", map[string]string{"If-Match": `W/"3"`}, 3, nil},
+		{"bare number", map[string]string{"If-Match": "3"}, 3, nil},
+		{"surrounding spaces", map[string]string{"If-Match": ` "3" `}, 3, nil},
+		{"derived etag", map[string]string{"If-Match": `"3-1a2b3c4d"`}, 3, nil},
+		{"zero", map[string]string{"If-Match": `"0"`}, 0, nil},
+		{"star", map[string]string{"If-Match": "*"}, Any, nil},
+		{"missing", nil, 0, ErrMissing},
+		{"empty", map[string]string{"If-Match": " "}, 0, ErrMissing},
+		{"not a number", map[string]string{"If-Match": `"abc"`}, 0, ErrInvalid},
+		{"negative", map[string]string{"If-Match": `"-1"`}, 0, ErrInvalid},
+		{"leading dash", map[string]string{"If-Match": `"-3"`}, 0, ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IfMatch(events.APIGatewayProxyRequest{Headers: tt.headers})
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("IfMatch(%v) = %d, %v, want %d, %v", tt.headers, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestETagWith(t *testing.T) {
+	etag := ETagWith(3, []byte("related"))
+	if !strings.HasPrefix(etag, `"3-`) || len(etag) != len(`"3-1a2b3c4d"`) {
+		t.Errorf("ETagWith = %s, want \"3-xxxxxxxx\"", etag)
+	}
+	if ETagWith(3, []byte("other")) == etag {
+		t.Error("ETagWith does not change with extra")
+	}
+	v, err := IfMatch(events.APIGatewayProxyRequest{Headers: map[string]string{"If-Match": etag}})
+	if err != nil || v != 3 {
+		t.Errorf("IfMatch(ETagWith) = %d, %v, want 3", v, err)
+	}
+	if got := ETag(3); got != `"3"` {
+		t.Errorf("ETag(3) = %s", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		expected, current int
+		wantMismatch      bool
+	}{
+		{3, 3, false},
+		{Any, 7, false},
+		{2, 3, true},
+		{0, 1, true},
+	}
+	for _, tt := range tests {
+		err := Check(tt.expected, tt.current)
+		var mismatch *MismatchError
+		if errors.As(err, &mismatch) != tt.wantMismatch {
+			t.Errorf("Check(%d, %d) = %v", tt.expected, tt.current, err)
+			continue
+		}
+		if tt.wantMismatch && mismatch.Current != tt.current {
+			t.Errorf("Check(%d, %d).Current = %d", tt.expected, tt.current, mismatch.Current)
+		}
+	}
+}
+
+func TestCondition(t *testing.T) {
+	expression, names, values := Condition(0)
+	if !strings.Contains(expression, "attribute_not_exists(#version)") {
+		t.Errorf("Condition(0) = %s, want items without version to match", expression)
+	}
+	if names["#version"] != "version" || values[":expectedVersion"].(*types.AttributeValueMemberN).Value != "0" {
+		t.Errorf("Condition(0) names, values = %v, %v", names, values)
+	}
+	if expression, _, values := Condition(4); expression != "#version = :expectedVersion" || values[":expectedVersion"].(*types.AttributeValueMemberN).Value != "4" {
+		t.Errorf("Condition(4) = %s, %v", expression, values)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantOK   bool
+	}{
+		{"missing", ErrMissing, 428, true},
+		{"invalid", ErrInvalid, 400, true},
+		{"mismatch", &MismatchError{Current: 5}, 412, true},
+		{"wrapped mismatch", fmt.Errorf("failed to check draft version: %w", &MismatchError{Current: 5}), 412, true},
+		{"other", errors.New("boom"), 0, false},
+		{"nil", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, ok := ErrorResponse(tt.err)
+			if ok != tt.wantOK || response.StatusCode != tt.wantCode {
+				t.Errorf("ErrorResponse(%v) = %d, %v, want %d, %v", tt.err, response.StatusCode, ok, tt.wantCode, tt.wantOK)
+			}
+			if tt.wantCode == 412 {
+				if response.Headers["ETag"] != `"5"` || !strings.Contains(response.Body, `"version":5`) {
+					t.Errorf("412 response = %v %s, want current version 5", response.Headers, response.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestConditionFailed(t *testing.T) {
+	canceled := &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+		{Code: aws.String("None")},
+		{Code: aws.String("ConditionalCheckFailed")},
+	}}
+	tests := []struct {
+		name  string
+		err   error
+		index int
+		want  bool
+	}{
+		{"single write", &types.ConditionalCheckFailedException{}, 0, true},
+		{"wrapped single write", fmt.Errorf("put: %w", &types.ConditionalCheckFailedException{}), 0, true},
+		{"transaction item that failed", canceled, 1, true},
+		{"transaction item that passed", canceled, 0, false},
+		{"index out of range", canceled, 2, false},
+		{"other", errors.New("boom"), 0, false},
+		{"nil", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConditionFailed(tt.err, tt.index); got != tt.want {
+				t.Errorf("ConditionFailed(%v, %d) = %v, want %v", tt.err, tt.index, got, tt.want)
+			}
+		})
+	}
+}